@@ -0,0 +1,152 @@
+package configurator
+
+import (
+	"bytes"
+	"errors"
+	"fmt"
+	"io/fs"
+	"os"
+	"path/filepath"
+	"slices"
+	"strings"
+	"time"
+)
+
+// backupSuffix ends the names of backups of configuration files (see WithBackups)
+const backupSuffix = ".bak"
+
+// backupTimeLayout is the layout of the (UTC) times naming backups of configuration files,
+// e.g., "config.env.20240102T150405.123456789Z.bak"
+const backupTimeLayout = "20060102T150405.000000000Z"
+
+// Backup identifies a backup of a configuration file (see WithBackups), holding its
+// contents as of Time
+type Backup struct {
+	File string
+	Time time.Time
+}
+
+// backupFileName returns the name of the backup of 'configFile' made at 'backupTime'
+func backupFileName(configFile string, backupTime time.Time) string {
+	return configFile + "." + backupTime.UTC().Format(backupTimeLayout) + backupSuffix
+}
+
+// ListBackups returns the backups of 'configFile' (see WithBackups), newest first
+func ListBackups(configFile string) ([]Backup, error) {
+	dirEntries, readErr := os.ReadDir(filepath.Dir(configFile))
+	if readErr != nil {
+		return nil, readErr
+	}
+	prefix := filepath.Base(configFile) + "."
+	var backups []Backup
+	for _, dirEntry := range dirEntries {
+		timestamp, isBackup := strings.CutPrefix(dirEntry.Name(), prefix)
+		if timestamp, isBackup = strings.CutSuffix(timestamp, backupSuffix); !isBackup || !dirEntry.Type().IsRegular() {
+			continue
+		}
+		backupTime, parseErr := time.Parse(backupTimeLayout, timestamp)
+		if parseErr != nil {
+			// e.g., made by a migration
+			continue
+		}
+		backups = append(backups, Backup{File: filepath.Join(filepath.Dir(configFile), dirEntry.Name()), Time: backupTime})
+	}
+	slices.SortFunc(backups, func(b1, b2 Backup) int {
+		return b2.Time.Compare(b1.Time)
+	})
+	return backups, nil
+}
+
+// backupConfigFile saves the contents of 'configFile' into a new backup, unless it doesn't exist
+// or its contents match those of its newest backup, then removes all but the newest 'count' backups
+func backupConfigFile(configFile string, count int, o *options) error {
+	contents, readErr := os.ReadFile(configFile)
+	if errors.Is(readErr, fs.ErrNotExist) {
+		return nil
+	}
+	if readErr != nil {
+		return &FileError{File: configFile, Err: readErr}
+	}
+	backups, listErr := ListBackups(configFile)
+	if listErr != nil {
+		return fmt.Errorf("can't back up(%s): %w", configFile, listErr)
+	}
+	if len(backups) == 0 || !sameContents(backups[0].File, contents) {
+		backupFile := backupFileName(configFile, time.Now())
+		if writeErr := os.WriteFile(backupFile, contents, 0600); writeErr != nil {
+			return fmt.Errorf("can't back up(%s): %w", configFile, writeErr)
+		}
+		backups = slices.Insert(backups, 0, Backup{File: backupFile})
+	}
+	for _, backup := range backups[min(count, len(backups)):] {
+		if removeErr := os.Remove(backup.File); removeErr != nil {
+			o.logger.Warn("error removing backup", "file", backup.File, "error", removeErr)
+		}
+	}
+	return nil
+}
+
+// sameContents returns true if 'fileName' holds 'contents'
+func sameContents(fileName string, contents []byte) bool {
+	fileContents, readErr := os.ReadFile(fileName)
+	return readErr == nil && bytes.Equal(fileContents, contents)
+}
+
+// DiffBackup returns the changes made to the entries of 'backupFile' (see ListBackups) by the
+// current contents of 'configFile', treating the entries named in 'secretNames' as secrets
+func DiffBackup(configFile, backupFile string, secretNames []string, opts ...Option) ([]ConfigChange, error) {
+	// the backup is written in the format of the configuration file, whatever its extension
+	opts = append(slices.Clip(opts), WithFormat(newOptions(opts).formatFor(configFile)))
+	return DiffConfigFiles(backupFile, configFile, secretNames, opts...)
+}
+
+// RestoreBackup atomically replaces the contents of 'configFile' with those of 'backupFile'
+// (see ListBackups), first backing up its current contents if given WithBackups.  Variables
+// loaded into the environment from entries missing from the backup are removed.
+func RestoreBackup(configFile, backupFile string, opts ...Option) error {
+	options := newOptions(opts)
+	contents, readErr := os.ReadFile(backupFile)
+	if readErr != nil {
+		return &FileError{File: backupFile, Err: readErr}
+	}
+	format := options.formatFor(configFile)
+	restoredEnv, parseErr := parseConfigFile(backupFile, contents, format)
+	if parseErr != nil {
+		return parseErr
+	}
+	if options.backups > 0 {
+		if backupErr := backupConfigFile(configFile, options.backups, options); backupErr != nil {
+			return backupErr
+		}
+	}
+	fileEnv, _ := readConfigFile(configFile, format)
+
+	tempFile, createErr := os.CreateTemp(filepath.Dir(configFile), filepath.Base(configFile)+".*.tmp")
+	if createErr != nil {
+		return fmt.Errorf("can't restore(%s): %w", configFile, createErr)
+	}
+	_, writeErr := tempFile.Write(contents)
+	if closeErr := tempFile.Close(); writeErr == nil {
+		writeErr = closeErr
+	}
+	if writeErr == nil {
+		writeErr = os.Rename(tempFile.Name(), configFile)
+	}
+	if writeErr != nil {
+		_ = os.Remove(tempFile.Name())
+		return fmt.Errorf("can't restore(%s): %w", configFile, writeErr)
+	}
+
+	for envName := range fileEnv {
+		if _, restored := restoredEnv[envName]; restored {
+			continue
+		}
+		if _, isExternal := lookupExternalEnv(envName); !isExternal {
+			if unsetErr := unsetManagedEnv(envName); unsetErr != nil {
+				return unsetErr
+			}
+		}
+	}
+	options.logger.Info("restored configuration file", "file", configFile, "backup", backupFile)
+	return nil
+}
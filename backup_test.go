@@ -0,0 +1,98 @@
+package configurator
+
+import (
+	"os"
+	"path/filepath"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/require"
+)
+
+func TestListBackups(t *testing.T) {
+
+	requirer := require.New(t)
+	tempDir := t.TempDir()
+	envFileName := filepath.Join(tempDir, "config.env")
+	older := time.Date(2024, 1, 2, 15, 4, 5, 0, time.UTC)
+	newer := older.Add(time.Millisecond)
+	for _, fileName := range []string{
+		backupFileName(envFileName, older),
+		backupFileName(envFileName, newer),
+		envFileName + ".v1.bak",
+		filepath.Join(tempDir, "other.env.20240102T150405.000000000Z.bak"),
+	} {
+		requirer.NoError(os.WriteFile(fileName, nil, 0600))
+	}
+
+	backups, listErr := ListBackups(envFileName)
+	requirer.NoError(listErr)
+	requirer.Equal([]Backup{
+		{File: filepath.Join(tempDir, "config.env.20240102T150405.001000000Z.bak"), Time: newer},
+		{File: filepath.Join(tempDir, "config.env.20240102T150405.000000000Z.bak"), Time: older},
+	}, backups)
+}
+
+func TestApiSaveRestoreBackups(t *testing.T) {
+
+	type testConfig struct {
+		Host string `env:"BACKUP_HOST"`
+		Port int    `env:"BACKUP_PORT"`
+	}
+
+	requirer := require.New(t)
+	unsetTestEnv(t, "BACKUP_HOST", "BACKUP_PORT")
+
+	envFileName := filepath.Join(t.TempDir(), "config.env")
+	opts := []Option{WithBackups(2), WithLogger(nil)}
+
+	// a missing file isn't backed up, nor are the same contents backed up twice in a row
+	for _, host := range []string{"host1", "host2", "host2", "host2"} {
+		requirer.NoError(SaveConfig(envFileName, testConfig{Host: host}, opts...))
+	}
+	backups, listErr := ListBackups(envFileName)
+	requirer.NoError(listErr)
+	requirer.Len(backups, 2)
+
+	// only the newest backups are kept
+	requirer.NoError(SaveConfigMap(envFileName, map[string]any{"BACKUP_HOST": "host3"}, opts...))
+	requirer.NoError(SaveConfigMap(envFileName, map[string]any{"BACKUP_HOST": "host4"}, opts...))
+	backups, listErr = ListBackups(envFileName)
+	requirer.NoError(listErr)
+	requirer.Len(backups, 2)
+	contents, readErr := os.ReadFile(backups[0].File)
+	requirer.NoError(readErr)
+	requirer.Equal("BACKUP_HOST=host3\n", string(contents))
+	contents, readErr = os.ReadFile(backups[1].File)
+	requirer.NoError(readErr)
+	requirer.Equal("BACKUP_HOST=host2\nBACKUP_PORT=0\n", string(contents))
+
+	changes, diffErr := DiffBackup(envFileName, backups[1].File, nil)
+	requirer.NoError(diffErr)
+	requirer.Equal([]ConfigChange{
+		{Name: "BACKUP_HOST", Kind: ChangeModified, OldVal: "host2", NewVal: "host4"},
+		{Name: "BACKUP_PORT", Kind: ChangeRemoved, OldVal: "0"},
+	}, changes)
+
+	// restoring a backup replaces the file, itself backed up
+	requirer.NoError(RestoreBackup(envFileName, backups[1].File, opts...))
+	contents, readErr = os.ReadFile(envFileName)
+	requirer.NoError(readErr)
+	requirer.Equal("BACKUP_HOST=host2\nBACKUP_PORT=0\n", string(contents))
+	backups, listErr = ListBackups(envFileName)
+	requirer.NoError(listErr)
+	requirer.Len(backups, 2)
+	contents, readErr = os.ReadFile(backups[0].File)
+	requirer.NoError(readErr)
+	requirer.Equal("BACKUP_HOST=host4\n", string(contents))
+	config := testConfig{}
+	requirer.NoError(LoadConfig(envFileName, &config))
+	requirer.Equal(testConfig{Host: "host2"}, config)
+
+	// values loaded from entries missing from the restored backup no longer apply
+	requirer.NoError(RestoreBackup(envFileName, backups[0].File))
+	_, found := os.LookupEnv("BACKUP_PORT")
+	requirer.False(found)
+
+	requirer.ErrorIs(RestoreBackup(envFileName, envFileName+".missing.bak"), os.ErrNotExist)
+}
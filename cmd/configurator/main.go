@@ -0,0 +1,359 @@
+// Command configurator manages the entries of configuration files (e.g., ".env" files)
+// without requiring a Go configuration structure, e.g., for use from shell scripts.
+//
+// Usage:
+//
+//	configurator [-file name] [-format dotenv|json|yaml|toml] [-schema name] [-backups count] [-quiet] command [arguments]
+//
+// Commands:
+//
+//	get NAME            print the value of NAME
+//	set NAME=VALUE...   set the value of each NAME
+//	unset NAME...       remove each NAME
+//	list                print all entries, as NAME=VALUE
+//	edit                invoke the interactive editor on all entries
+//	validate            check the file can be read and its names are valid
+//	backups             print the backups of the file, newest first, as NUMBER FILE
+//	diff [BACKUP]       print the changes made to the file since BACKUP
+//	restore [BACKUP]    replace the file with BACKUP
+//
+// BACKUP is given by its number or file name, as printed by "backups"; by default,
+// the newest backup.  Given "-backups", that many backups of the file are kept when
+// it's changed (see configurator.WithBackups).
+//
+// When a schema (e.g., as written by configurator.WriteConfigSchema) is given, "edit"
+// presents the entries it describes, values are validated against it, and "diff" masks
+// the values of those it describes as secrets.
+package main
+
+import (
+	"errors"
+	"flag"
+	"fmt"
+	"io"
+	"log/slog"
+	"os"
+	"regexp"
+	"sort"
+	"strconv"
+	"strings"
+
+	"github.com/noodnik2/configurator"
+)
+
+// formats maps the names accepted by the "-format" flag to the formats they name
+var formats = map[string]configurator.Format{
+	"dotenv": configurator.DotenvFormat,
+	"json":   configurator.JSONFormat,
+	"yaml":   configurator.YAMLFormat,
+	"toml":   configurator.TOMLFormat,
+}
+
+// envNamePattern matches valid (POSIX) environment variable names
+var envNamePattern = regexp.MustCompile(`^[A-Za-z_][A-Za-z0-9_]*$`)
+
+func main() {
+	if err := run(os.Args[1:], os.Stdout, os.Stderr); err != nil {
+		fmt.Fprintf(os.Stderr, "configurator: %v\n", err)
+		os.Exit(1)
+	}
+}
+
+// run executes the command given in 'args', writing its output to 'stdout'
+// and usage information to 'stderr'
+func run(args []string, stdout, stderr io.Writer) error {
+	flagSet := flag.NewFlagSet("configurator", flag.ContinueOnError)
+	flagSet.SetOutput(stderr)
+	fileName := flagSet.String("file", ".env", "name of the configuration file")
+	formatName := flagSet.String("format", "", "format of the configuration file (dotenv, json, yaml or toml); by default, indicated by its extension")
+	schemaFileName := flagSet.String("schema", "", "name of a JSON Schema (or YAML manifest) describing the configuration file")
+	backupCount := flagSet.Int("backups", 0, "number of backups of the configuration file to keep when changing it")
+	quiet := flagSet.Bool("quiet", false, "don't report notes (e.g., about values shadowed by the environment)")
+	flagSet.Usage = func() {
+		fmt.Fprintln(stderr, "usage: configurator [flags] get NAME | set NAME=VALUE... | unset NAME... | list | edit | validate | backups | diff [BACKUP] | restore [BACKUP]")
+		flagSet.PrintDefaults()
+	}
+	if parseErr := flagSet.Parse(args); parseErr != nil {
+		return parseErr
+	}
+
+	logger := slog.New(slog.NewTextHandler(stderr, nil))
+	if *quiet {
+		logger = nil
+	}
+	opts := []configurator.Option{configurator.WithLogger(logger)}
+	if *formatName != "" {
+		format, found := formats[strings.ToLower(*formatName)]
+		if !found {
+			return fmt.Errorf("unsupported format(%s)", *formatName)
+		}
+		opts = append(opts, configurator.WithFormat(format))
+	}
+	if *backupCount > 0 {
+		opts = append(opts, configurator.WithBackups(*backupCount))
+	}
+
+	var schema *configurator.Schema
+	if *schemaFileName != "" {
+		var schemaErr error
+		if schema, schemaErr = configurator.ReadSchemaFile(*schemaFileName); schemaErr != nil {
+			return schemaErr
+		}
+	}
+
+	if flagSet.NArg() == 0 {
+		flagSet.Usage()
+		return errors.New("missing command")
+	}
+	command, commandArgs := flagSet.Arg(0), flagSet.Args()[1:]
+	switch command {
+	case "get":
+		return runGet(*fileName, commandArgs, stdout, opts)
+	case "set":
+		return runSet(*fileName, commandArgs, schema, opts)
+	case "unset":
+		return runUnset(*fileName, commandArgs, opts)
+	case "list":
+		return runList(*fileName, commandArgs, stdout, opts)
+	case "edit":
+		return runEdit(*fileName, commandArgs, schema, opts)
+	case "validate":
+		return runValidate(*fileName, commandArgs, schema, stdout, opts)
+	case "backups":
+		return runBackups(*fileName, commandArgs, stdout)
+	case "diff":
+		return runDiff(*fileName, commandArgs, schema, stdout, opts)
+	case "restore":
+		return runRestore(*fileName, commandArgs, opts)
+	}
+	flagSet.Usage()
+	return fmt.Errorf("unknown command(%s)", command)
+}
+
+func runGet(fileName string, args []string, stdout io.Writer, opts []configurator.Option) error {
+	if len(args) != 1 {
+		return errors.New("usage: get NAME")
+	}
+	configMap, readErr := configurator.ReadConfigMap(fileName, opts...)
+	if readErr != nil {
+		return readErr
+	}
+	envVal, found := configMap[args[0]]
+	if !found {
+		return fmt.Errorf("%s not found in %s", args[0], fileName)
+	}
+	_, writeErr := fmt.Fprintln(stdout, envVal)
+	return writeErr
+}
+
+func runSet(fileName string, args []string, schema *configurator.Schema, opts []configurator.Option) error {
+	if len(args) == 0 {
+		return errors.New("usage: set NAME=VALUE...")
+	}
+	configMap, readErr := readConfigMapIfExists(fileName, opts)
+	if readErr != nil {
+		return readErr
+	}
+	for _, arg := range args {
+		envName, envVal, hasVal := strings.Cut(arg, "=")
+		if !hasVal {
+			return fmt.Errorf("missing value in(%s); expected NAME=VALUE", arg)
+		}
+		if !envNamePattern.MatchString(envName) {
+			return fmt.Errorf("invalid name(%s)", envName)
+		}
+		if schema != nil && schema.Properties[envName] != nil {
+			// entries not described by the schema are allowed
+			if validateErr := schema.ValidateValue(envName, envVal); validateErr != nil {
+				return validateErr
+			}
+		}
+		configMap[envName] = envVal
+	}
+	return configurator.SaveConfigMap(fileName, configMap, opts...)
+}
+
+func runUnset(fileName string, args []string, opts []configurator.Option) error {
+	if len(args) == 0 {
+		return errors.New("usage: unset NAME...")
+	}
+	configMap, readErr := configurator.ReadConfigMap(fileName, opts...)
+	if readErr != nil {
+		return readErr
+	}
+	for _, envName := range args {
+		// entries having nil values are removed from the file
+		configMap[envName] = nil
+	}
+	return configurator.SaveConfigMap(fileName, configMap, opts...)
+}
+
+func runList(fileName string, args []string, stdout io.Writer, opts []configurator.Option) error {
+	if len(args) != 0 {
+		return errors.New("usage: list")
+	}
+	configMap, readErr := configurator.ReadConfigMap(fileName, opts...)
+	if readErr != nil {
+		return readErr
+	}
+	for _, envName := range sortedNames(configMap) {
+		if _, writeErr := fmt.Fprintf(stdout, "%s=%v\n", envName, configMap[envName]); writeErr != nil {
+			return writeErr
+		}
+	}
+	return nil
+}
+
+func runEdit(fileName string, args []string, schema *configurator.Schema, opts []configurator.Option) error {
+	if len(args) != 0 {
+		return errors.New("usage: edit")
+	}
+	if schema == nil {
+		configMap, readErr := configurator.ReadConfigMap(fileName, opts...)
+		if readErr != nil {
+			return readErr
+		}
+		if editErr := configurator.EditConfigMap(configMap, opts...); editErr != nil {
+			return editErr
+		}
+		return configurator.SaveConfigMap(fileName, configMap, opts...)
+	}
+	// the schema describes the entries, so they needn't yet exist
+	configMap, readErr := readConfigMapIfExists(fileName, opts)
+	if readErr != nil {
+		return readErr
+	}
+	if editErr := configurator.EditConfigMapWithSchema(configMap, schema, opts...); editErr != nil {
+		return editErr
+	}
+	return configurator.SaveConfigMap(fileName, configMap, opts...)
+}
+
+func runValidate(fileName string, args []string, schema *configurator.Schema, stdout io.Writer, opts []configurator.Option) error {
+	if len(args) != 0 {
+		return errors.New("usage: validate")
+	}
+	configMap, readErr := configurator.ReadConfigMap(fileName, opts...)
+	if readErr != nil {
+		return readErr
+	}
+	var invalidNames []string
+	for _, envName := range sortedNames(configMap) {
+		if !envNamePattern.MatchString(envName) {
+			invalidNames = append(invalidNames, envName)
+		}
+	}
+	if len(invalidNames) != 0 {
+		return fmt.Errorf("invalid name(s) in %s: %s", fileName, strings.Join(invalidNames, ", "))
+	}
+	if schema != nil {
+		if validateErr := schema.Validate(configMap); validateErr != nil {
+			return fmt.Errorf("%s doesn't conform to schema:\n%w", fileName, validateErr)
+		}
+	}
+	_, writeErr := fmt.Fprintf(stdout, "%s: %d valid entries\n", fileName, len(configMap))
+	return writeErr
+}
+
+func runBackups(fileName string, args []string, stdout io.Writer) error {
+	if len(args) != 0 {
+		return errors.New("usage: backups")
+	}
+	backups, listErr := configurator.ListBackups(fileName)
+	if listErr != nil {
+		return listErr
+	}
+	for i, backup := range backups {
+		if _, writeErr := fmt.Fprintf(stdout, "%d %s\n", i+1, backup.File); writeErr != nil {
+			return writeErr
+		}
+	}
+	return nil
+}
+
+func runDiff(fileName string, args []string, schema *configurator.Schema, stdout io.Writer, opts []configurator.Option) error {
+	if len(args) > 1 {
+		return errors.New("usage: diff [BACKUP]")
+	}
+	backupFile, backupErr := findBackup(fileName, args)
+	if backupErr != nil {
+		return backupErr
+	}
+	changes, diffErr := configurator.DiffBackup(fileName, backupFile, secretNames(schema), opts...)
+	if diffErr != nil {
+		return diffErr
+	}
+	for _, change := range changes {
+		if _, writeErr := fmt.Fprintln(stdout, change); writeErr != nil {
+			return writeErr
+		}
+	}
+	return nil
+}
+
+// secretNames returns the names of the entries described by 'schema' as secrets (i.e., having
+// "x-secret" or "writeOnly" properties), if given
+func secretNames(schema *configurator.Schema) []string {
+	if schema == nil {
+		return nil
+	}
+	var names []string
+	for envName, property := range schema.Properties {
+		if property != nil && (property.Secret != "" || property.WriteOnly) {
+			names = append(names, envName)
+		}
+	}
+	return names
+}
+
+func runRestore(fileName string, args []string, opts []configurator.Option) error {
+	if len(args) > 1 {
+		return errors.New("usage: restore [BACKUP]")
+	}
+	backupFile, backupErr := findBackup(fileName, args)
+	if backupErr != nil {
+		return backupErr
+	}
+	return configurator.RestoreBackup(fileName, backupFile, opts...)
+}
+
+// findBackup returns the name of the backup of 'fileName' given in 'args' by its number
+// or file name (as printed by "backups"), or that of the newest backup if not given
+func findBackup(fileName string, args []string) (string, error) {
+	if len(args) == 1 {
+		if _, atoiErr := strconv.Atoi(args[0]); atoiErr != nil {
+			return args[0], nil
+		}
+	}
+	backups, listErr := configurator.ListBackups(fileName)
+	if listErr != nil {
+		return "", listErr
+	}
+	number := 1
+	if len(args) == 1 {
+		number, _ = strconv.Atoi(args[0])
+	}
+	if number < 1 || number > len(backups) {
+		return "", fmt.Errorf("no backup(%d) of %s", number, fileName)
+	}
+	return backups[number-1].File, nil
+}
+
+// readConfigMapIfExists returns the entries of 'fileName', or an empty map if it doesn't exist
+func readConfigMapIfExists(fileName string, opts []configurator.Option) (map[string]any, error) {
+	configMap, readErr := configurator.ReadConfigMap(fileName, opts...)
+	if errors.Is(readErr, os.ErrNotExist) {
+		return make(map[string]any), nil
+	}
+	return configMap, readErr
+}
+
+// sortedNames returns the names of the entries of 'configMap', in order
+func sortedNames(configMap map[string]any) []string {
+	names := make([]string, 0, len(configMap))
+	for name := range configMap {
+		names = append(names, name)
+	}
+	sort.Strings(names)
+	return names
+}
@@ -0,0 +1,189 @@
+package main
+
+import (
+	"bytes"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+
+	"github.com/stretchr/testify/require"
+)
+
+func TestRun(t *testing.T) {
+
+	requirer := require.New(t)
+	unsetTestEnv(t, "CLI_A", "CLI_B")
+
+	fileName := filepath.Join(t.TempDir(), "config.env")
+	runCli := func(args ...string) (string, error) {
+		stdout := &bytes.Buffer{}
+		runErr := run(append([]string{"-file", fileName}, args...), stdout, &bytes.Buffer{})
+		return stdout.String(), runErr
+	}
+
+	_, runErr := runCli("set", "CLI_A=a value", "CLI_B=0042")
+	requirer.NoError(runErr)
+	contents, readErr := os.ReadFile(fileName)
+	requirer.NoError(readErr)
+	requirer.Equal("CLI_A=a value\nCLI_B=0042\n", string(contents))
+
+	output, runErr := runCli("get", "CLI_B")
+	requirer.NoError(runErr)
+	requirer.Equal("0042\n", output)
+
+	output, runErr = runCli("list")
+	requirer.NoError(runErr)
+	requirer.Equal("CLI_A=a value\nCLI_B=0042\n", output)
+
+	_, runErr = runCli("unset", "CLI_A")
+	requirer.NoError(runErr)
+	output, runErr = runCli("list")
+	requirer.NoError(runErr)
+	requirer.Equal("CLI_B=0042\n", output)
+
+	output, runErr = runCli("validate")
+	requirer.NoError(runErr)
+	requirer.Contains(output, "1 valid entries")
+
+	// values are read back as set, never injecting other entries
+	for _, envVal := range []string{"a # b", "  padded  ", "'quoted'", `"quoted"`, "x\nINJECTED=evil"} {
+		_, runErr = runCli("set", "CLI_A="+envVal)
+		requirer.NoError(runErr)
+		output, runErr = runCli("get", "CLI_A")
+		requirer.NoError(runErr)
+		requirer.Equal(envVal+"\n", output)
+		_, runErr = runCli("get", "INJECTED")
+		requirer.ErrorContains(runErr, "INJECTED not found")
+	}
+	_, runErr = runCli("unset", "CLI_A")
+	requirer.NoError(runErr)
+
+	_, runErr = runCli("get", "CLI_A")
+	requirer.ErrorContains(runErr, "CLI_A not found")
+	_, runErr = runCli("set", "CLI-C=x")
+	requirer.ErrorContains(runErr, "invalid name(CLI-C)")
+	_, runErr = runCli("set", "CLI_C")
+	requirer.ErrorContains(runErr, "missing value")
+	_, runErr = runCli("nonesuch")
+	requirer.ErrorContains(runErr, "unknown command(nonesuch)")
+	_, runErr = runCli()
+	requirer.ErrorContains(runErr, "missing command")
+}
+
+func TestRunWithFormat(t *testing.T) {
+
+	requirer := require.New(t)
+	unsetTestEnv(t, "CLI_J")
+
+	fileName := filepath.Join(t.TempDir(), "config")
+	requirer.NoError(run([]string{"-file", fileName, "-format", "json", "set", "CLI_J=j"}, &bytes.Buffer{}, &bytes.Buffer{}))
+	contents, readErr := os.ReadFile(fileName)
+	requirer.NoError(readErr)
+	requirer.Equal("{\n  \"CLI_J\": \"j\"\n}\n", string(contents))
+
+	requirer.ErrorContains(run([]string{"-format", "xml", "list"}, &bytes.Buffer{}, &bytes.Buffer{}), "unsupported format(xml)")
+
+	requirer.NoError(os.WriteFile(fileName, []byte("{\"BAD NAME\": \"x\"}"), 0600))
+	requirer.ErrorContains(run([]string{"-file", fileName, "-format", "json", "validate"}, &bytes.Buffer{}, &bytes.Buffer{}), "invalid name(s)")
+}
+
+func TestRunWithSchema(t *testing.T) {
+
+	requirer := require.New(t)
+	unsetTestEnv(t, "CLI_PORT", "CLI_OTHER")
+
+	tempDir := t.TempDir()
+	schemaFileName := filepath.Join(tempDir, "schema.yaml")
+	requirer.NoError(os.WriteFile(schemaFileName, []byte("required: [CLI_PORT]\nproperties:\n  CLI_PORT: {type: integer}\n"), 0600))
+	fileName := filepath.Join(tempDir, "config.env")
+	runCli := func(args ...string) error {
+		return run(append([]string{"-file", fileName, "-schema", schemaFileName}, args...), &bytes.Buffer{}, &bytes.Buffer{})
+	}
+
+	requirer.ErrorContains(runCli("set", "CLI_PORT=eighty"), `invalid value("eighty") for(CLI_PORT): isn't of type(integer)`)
+	requirer.NoError(runCli("set", "CLI_OTHER=x"))
+	requirer.ErrorContains(runCli("validate"), "invalid(CLI_PORT): missing required value")
+	requirer.NoError(runCli("set", "CLI_PORT=80"))
+	requirer.NoError(runCli("validate"))
+}
+
+func TestRunWithBackups(t *testing.T) {
+
+	requirer := require.New(t)
+	unsetTestEnv(t, "CLI_K")
+
+	fileName := filepath.Join(t.TempDir(), "config.env")
+	runCli := func(args ...string) (string, error) {
+		stdout := &bytes.Buffer{}
+		runErr := run(append([]string{"-file", fileName, "-backups", "2"}, args...), stdout, &bytes.Buffer{})
+		return stdout.String(), runErr
+	}
+
+	_, runErr := runCli("diff")
+	requirer.ErrorContains(runErr, "no backup(1) of "+fileName)
+	for _, arg := range []string{"CLI_K=1", "CLI_K=2", "CLI_K=3"} {
+		_, runErr = runCli("set", arg)
+		requirer.NoError(runErr)
+	}
+
+	output, runErr := runCli("backups")
+	requirer.NoError(runErr)
+	lines := strings.Split(strings.TrimSuffix(output, "\n"), "\n")
+	requirer.Len(lines, 2)
+	requirer.True(strings.HasPrefix(lines[0], "1 "+fileName+"."))
+
+	output, runErr = runCli("diff", "2")
+	requirer.NoError(runErr)
+	requirer.Equal("~CLI_K: 1 -> 3\n", output)
+
+	_, runErr = runCli("restore")
+	requirer.NoError(runErr)
+	output, runErr = runCli("get", "CLI_K")
+	requirer.NoError(runErr)
+	requirer.Equal("2\n", output)
+
+	_, runErr = runCli("restore", "3")
+	requirer.ErrorContains(runErr, "no backup(3)")
+}
+
+func TestRunDiffWithSchema(t *testing.T) {
+
+	requirer := require.New(t)
+	unsetTestEnv(t, "CLI_USER", "CLI_TOKEN")
+
+	tempDir := t.TempDir()
+	schemaFileName := filepath.Join(tempDir, "schema.yaml")
+	requirer.NoError(os.WriteFile(schemaFileName, []byte("properties:\n  CLI_USER: {type: string}\n  CLI_TOKEN: {type: string, writeOnly: true}\n"), 0600))
+	fileName := filepath.Join(tempDir, "config.env")
+	runCli := func(args ...string) (string, error) {
+		stdout := &bytes.Buffer{}
+		runErr := run(append([]string{"-file", fileName, "-backups", "1"}, args...), stdout, &bytes.Buffer{})
+		return stdout.String(), runErr
+	}
+
+	_, runErr := runCli("set", "CLI_USER=u1", "CLI_TOKEN=t0ken1")
+	requirer.NoError(runErr)
+	_, runErr = runCli("set", "CLI_USER=u2", "CLI_TOKEN=t0ken2")
+	requirer.NoError(runErr)
+
+	// the values of secrets described by the schema are masked
+	output, runErr := runCli("-schema", schemaFileName, "diff")
+	requirer.NoError(runErr)
+	requirer.NotContains(output, "t0ken")
+	requirer.Contains(output, "~CLI_USER: u1 -> u2\n")
+	requirer.Contains(output, "~CLI_TOKEN: ")
+
+	// while they're printed without it
+	output, runErr = runCli("diff")
+	requirer.NoError(runErr)
+	requirer.Contains(output, "~CLI_TOKEN: t0ken1 -> t0ken2\n")
+}
+
+// unsetTestEnv unsets the variables named 'envNames' for the duration of the test
+func unsetTestEnv(t *testing.T, envNames ...string) {
+	for _, envName := range envNames {
+		t.Setenv(envName, "")
+		require.NoError(t, os.Unsetenv(envName))
+	}
+}
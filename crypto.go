@@ -0,0 +1,69 @@
+package configurator
+
+import (
+	"crypto/aes"
+	"crypto/cipher"
+	"crypto/rand"
+	"errors"
+	"fmt"
+
+	"golang.org/x/crypto/scrypt"
+)
+
+const (
+	cryptoKeySize  = 32 // selects AES-256
+	cryptoSaltSize = 16
+)
+
+// deriveKey derives an encryption key from 'passphrase' using scrypt
+func deriveKey(passphrase, salt []byte) ([]byte, error) {
+	return scrypt.Key(passphrase, salt, 1<<15, 8, 1, cryptoKeySize)
+}
+
+// newSalt returns a new random salt for use with deriveKey
+func newSalt() ([]byte, error) {
+	salt := make([]byte, cryptoSaltSize)
+	if _, readErr := rand.Read(salt); readErr != nil {
+		return nil, readErr
+	}
+	return salt, nil
+}
+
+// seal encrypts and authenticates 'plaintext' (along with 'additionalData', if any, which
+// must be given again to unseal it) using AES-GCM, returning the random nonce followed by
+// the ciphertext
+func seal(key, plaintext, additionalData []byte) ([]byte, error) {
+	aead, aeadErr := newAead(key)
+	if aeadErr != nil {
+		return nil, aeadErr
+	}
+	nonce := make([]byte, aead.NonceSize(), aead.NonceSize()+len(plaintext)+aead.Overhead())
+	if _, readErr := rand.Read(nonce); readErr != nil {
+		return nil, readErr
+	}
+	return aead.Seal(nonce, nonce, plaintext, additionalData), nil
+}
+
+// unseal reverses seal, failing if 'sealed' wasn't produced by seal using 'key' and 'additionalData'
+func unseal(key, sealed, additionalData []byte) ([]byte, error) {
+	aead, aeadErr := newAead(key)
+	if aeadErr != nil {
+		return nil, aeadErr
+	}
+	if len(sealed) < aead.NonceSize() {
+		return nil, errors.New("sealed value too short")
+	}
+	plaintext, openErr := aead.Open(nil, sealed[:aead.NonceSize()], sealed[aead.NonceSize():], additionalData)
+	if openErr != nil {
+		return nil, fmt.Errorf("can't decrypt; wrong key or corrupted value: %w", openErr)
+	}
+	return plaintext, nil
+}
+
+func newAead(key []byte) (cipher.AEAD, error) {
+	block, cipherErr := aes.NewCipher(key)
+	if cipherErr != nil {
+		return nil, cipherErr
+	}
+	return cipher.NewGCM(block)
+}
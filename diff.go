@@ -0,0 +1,212 @@
+package configurator
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"io/fs"
+	"os"
+)
+
+// ChangeKind identifies the kind of change made to a configuration entry
+type ChangeKind string
+
+const (
+	ChangeAdded    ChangeKind = "added"
+	ChangeRemoved  ChangeKind = "removed"
+	ChangeModified ChangeKind = "modified"
+)
+
+// ConfigChange describes a change made to a configuration entry.  The values of
+// entries known to be secret are given as their display values (see DisplayVal).
+type ConfigChange struct {
+	Name   string
+	Kind   ChangeKind
+	OldVal any // nil if added
+	NewVal any // nil if removed
+}
+
+// String renders the change as "+NAME=new", "-NAME=old" or "~NAME: old -> new"
+func (cc ConfigChange) String() string {
+	switch cc.Kind {
+	case ChangeAdded:
+		return fmt.Sprintf("+%s=%v", cc.Name, cc.NewVal)
+	case ChangeRemoved:
+		return fmt.Sprintf("-%s=%v", cc.Name, cc.OldVal)
+	default:
+		return fmt.Sprintf("~%s: %v -> %v", cc.Name, cc.OldVal, cc.NewVal)
+	}
+}
+
+// DiffConfigs returns the changes made to the environment items of 'oldConfig' by 'newConfig'
+func DiffConfigs[T any](oldConfig, newConfig T) ([]ConfigChange, error) {
+	oldMap, secrets, oldErr := getConfigDiffMap(oldConfig)
+	if oldErr != nil {
+		return nil, oldErr
+	}
+	newMap, _, newErr := getConfigDiffMap(newConfig)
+	if newErr != nil {
+		return nil, newErr
+	}
+	return diffConfigMaps(oldMap, newMap, secrets), nil
+}
+
+// DiffConfigFile returns the changes saving 'config' (see SaveConfig) would make to the entries of
+// 'configFile' (overlaid by those of the configuration file of the selected profile, if any; see
+// WithProfile), e.g., to warn about unsaved changes; a missing file is treated as having no entries.
+// Values saved elsewhere (e.g., into a SecretStore or value files) aren't compared, and encrypted
+// envelopes are compared by the values within them.
+func DiffConfigFile[T any](configFile string, config T, opts ...Option) ([]ConfigChange, error) {
+	options := newOptions(opts)
+	envItems, getterErr := GetConfigEnvItems(config)
+	if getterErr != nil {
+		return nil, getterErr
+	}
+	fileEnv, _, readErr := readConfigLayers(configFile, options)
+	if errors.Is(readErr, fs.ErrNotExist) {
+		fileEnv, readErr = map[string]string{}, nil
+	}
+	if readErr != nil {
+		return nil, readErr
+	}
+	fileMap := make(map[string]any, len(fileEnv))
+	for envName, envVal := range fileEnv {
+		if options.encryptionKey != nil {
+			decrypted, decryptErr := options.decryptMutator(context.Background(), envName, envVal)
+			if decryptErr != nil {
+				return nil, decryptErr
+			}
+			envVal = decrypted
+		}
+		fileMap[envName] = envVal
+	}
+	secrets := make(map[string]string)
+	for _, envItem := range envItems {
+		if envItem.IsSecret() {
+			secrets[envItem.Name] = envItem.Secret
+		}
+	}
+	saved := getSavedEntries(configFile, envItems, options)
+	return diffConfigMaps(fileMap, options.withVersion(saved.configMap), secrets), nil
+}
+
+// DiffConfigFiles returns the changes made to the entries of 'oldConfigFile' by 'newConfigFile',
+// treating the entries named in 'secretNames' as secrets; a missing file is treated as having
+// no entries
+func DiffConfigFiles(oldConfigFile, newConfigFile string, secretNames []string, opts ...Option) ([]ConfigChange, error) {
+	oldMap, oldErr := readDiffConfigMap(oldConfigFile, opts)
+	if oldErr != nil {
+		return nil, oldErr
+	}
+	newMap, newErr := readDiffConfigMap(newConfigFile, opts)
+	if newErr != nil {
+		return nil, newErr
+	}
+	return DiffConfigMaps(oldMap, newMap, secretNames...), nil
+}
+
+// DiffConfigMaps returns the changes made to the entries of 'oldMap' by 'newMap' (as given to
+// SaveConfigMap), treating the entries named in 'secretNames' as secrets
+func DiffConfigMaps(oldMap, newMap map[string]any, secretNames ...string) []ConfigChange {
+	secrets := make(map[string]string, len(secretNames))
+	for _, secretName := range secretNames {
+		secrets[secretName] = SecretHide
+	}
+	return diffConfigMaps(oldMap, newMap, secrets)
+}
+
+// diffConfigMaps returns the changes made to the entries of 'oldMap' by 'newMap', in order
+// of their names; entries with nil values are considered missing, and values are compared
+// as they'd be saved.  'secrets' maps the names of secret entries to their `secret` tags.
+func diffConfigMaps(oldMap, newMap map[string]any, secrets map[string]string) []ConfigChange {
+	allNames := make(map[string]bool, len(oldMap)+len(newMap))
+	for name := range oldMap {
+		allNames[name] = true
+	}
+	for name := range newMap {
+		allNames[name] = true
+	}
+
+	var changes []ConfigChange
+	for _, name := range sortedKeys(allNames) {
+		oldVal, newVal := oldMap[name], newMap[name]
+		var change ConfigChange
+		switch {
+		case oldVal == nil && newVal == nil:
+			continue
+		case oldVal == nil:
+			change = ConfigChange{Name: name, Kind: ChangeAdded}
+		case newVal == nil:
+			change = ConfigChange{Name: name, Kind: ChangeRemoved}
+		case fmt.Sprintf("%v", oldVal) != fmt.Sprintf("%v", newVal):
+			change = ConfigChange{Name: name, Kind: ChangeModified}
+		default:
+			continue
+		}
+		if oldVal != nil {
+			change.OldVal = ConfigEnvItem{Val: oldVal, Secret: secrets[name]}.DisplayVal()
+		}
+		if newVal != nil {
+			change.NewVal = ConfigEnvItem{Val: newVal, Secret: secrets[name]}.DisplayVal()
+		}
+		changes = append(changes, change)
+	}
+	return changes
+}
+
+// getConfigDiffMap returns the map of the environment items of 'config' (as built by
+// SaveConfig) along with the `secret` tags of those which are secret
+func getConfigDiffMap[T any](config T) (map[string]any, map[string]string, error) {
+	envItems, getterErr := GetConfigEnvItems(config)
+	if getterErr != nil {
+		return nil, nil, getterErr
+	}
+	configMap := make(map[string]any, len(envItems))
+	secrets := make(map[string]string)
+	for _, envItem := range envItems {
+		configMap[envItem.Name] = envItem.Val
+		if envItem.IsSecret() {
+			secrets[envItem.Name] = envItem.Secret
+		}
+	}
+	return configMap, secrets, nil
+}
+
+// readDiffConfigMap reads the entries of 'configFile', treating a missing file as having none;
+// references to variables are expanded unless given WithoutInterpolation (see interpolate), and
+// encrypted envelopes are decrypted if an encryption key is given (see WithEncryptionKey)
+func readDiffConfigMap(configFile string, opts []Option) (map[string]any, error) {
+	options := newOptions(opts)
+	format := options.formatFor(configFile)
+	contents, readErr := os.ReadFile(configFile)
+	if errors.Is(readErr, fs.ErrNotExist) {
+		return map[string]any{}, nil
+	}
+	if readErr != nil {
+		return nil, &FileError{File: configFile, Err: readErr}
+	}
+	fileEnv, parseErr := parseConfigFile(configFile, contents, format)
+	if parseErr != nil {
+		return nil, parseErr
+	}
+
+	expandedEnv, interpolateErr := options.interpolate(options.templatesOf(contents, format, fileEnv))
+	if interpolateErr != nil {
+		return nil, interpolateErr
+	}
+	configMap := make(map[string]any, len(expandedEnv))
+	for envName, envVal := range expandedEnv {
+		configMap[envName] = envVal
+	}
+	if options.encryptionKey == nil {
+		return configMap, nil
+	}
+	for envName, envVal := range configMap {
+		decrypted, decryptErr := options.decryptMutator(context.Background(), envName, envVal.(string))
+		if decryptErr != nil {
+			return nil, decryptErr
+		}
+		configMap[envName] = decrypted
+	}
+	return configMap, nil
+}
@@ -0,0 +1,118 @@
+package configurator
+
+import (
+	"os"
+	"path/filepath"
+	"testing"
+
+	"github.com/stretchr/testify/require"
+)
+
+func TestDiffConfigs(t *testing.T) {
+
+	type testConfig struct {
+		S1 string  `env:"DIFF_S1"`
+		F2 float32 `env:"DIFF_F2"`
+		S3 string  `env:"DIFF_S3" secret:"mask"`
+		S4 string  `env:"DIFF_S4" secret:"hide"`
+	}
+
+	requirer := require.New(t)
+
+	oldConfig := testConfig{S1: "same", F2: 1.5, S3: "1234", S4: "old secret"}
+	changes, diffErr := DiffConfigs(oldConfig, oldConfig)
+	requirer.NoError(diffErr)
+	requirer.Empty(changes)
+
+	newConfig := testConfig{S1: "same", F2: 2.5, S3: "12345", S4: "new secret"}
+	changes, diffErr = DiffConfigs(oldConfig, newConfig)
+	requirer.NoError(diffErr)
+	requirer.Equal([]ConfigChange{
+		{Name: "DIFF_F2", Kind: ChangeModified, OldVal: float32(1.5), NewVal: float32(2.5)},
+		{Name: "DIFF_S3", Kind: ChangeModified, OldVal: "****", NewVal: "*****"},
+		{Name: "DIFF_S4", Kind: ChangeModified, OldVal: "<suppressed>", NewVal: "<suppressed>"},
+	}, changes)
+	requirer.Equal("~DIFF_F2: 1.5 -> 2.5", changes[0].String())
+}
+
+func TestDiffConfigMaps(t *testing.T) {
+
+	requirer := require.New(t)
+
+	changes := DiffConfigMaps(
+		map[string]any{"KEPT": "1", "GONE": "bye", "SECRET": "old", "NIL": nil},
+		map[string]any{"KEPT": 1, "NEW": "hi", "SECRET": "new", "NIL": nil},
+		"SECRET",
+	)
+	requirer.Equal([]ConfigChange{
+		{Name: "GONE", Kind: ChangeRemoved, OldVal: "bye"},
+		{Name: "NEW", Kind: ChangeAdded, NewVal: "hi"},
+		{Name: "SECRET", Kind: ChangeModified, OldVal: "<suppressed>", NewVal: "<suppressed>"},
+	}, changes)
+	requirer.Equal("-GONE=bye", changes[0].String())
+	requirer.Equal("+NEW=hi", changes[1].String())
+}
+
+func TestDiffConfigFiles(t *testing.T) {
+
+	type testConfig struct {
+		S1 string `env:"DIFFF_S1"`
+		I2 int    `env:"DIFFF_I2"`
+		S3 string `env:"DIFFF_S3" secret:"hide"`
+	}
+
+	requirer := require.New(t)
+	tempDir := t.TempDir()
+	oldFileName := filepath.Join(tempDir, "old.env")
+	newFileName := filepath.Join(tempDir, "new.yaml")
+	requirer.NoError(os.WriteFile(oldFileName, []byte("DIFFF_S1=one\nDIFFF_I2=2\nDIFFF_S3=secret\nDIFFF_X=extra\n"), 0600))
+	requirer.NoError(os.WriteFile(newFileName, []byte("DIFFF_S1: one\nDIFFF_I2: 3\n"), 0600))
+
+	// file vs. file
+	changes, diffErr := DiffConfigFiles(oldFileName, newFileName, []string{"DIFFF_S3"})
+	requirer.NoError(diffErr)
+	requirer.Equal([]ConfigChange{
+		{Name: "DIFFF_I2", Kind: ChangeModified, OldVal: "2", NewVal: "3"},
+		{Name: "DIFFF_S3", Kind: ChangeRemoved, OldVal: "<suppressed>"},
+		{Name: "DIFFF_X", Kind: ChangeRemoved, OldVal: "extra"},
+	}, changes)
+
+	// file vs. memory
+	changes, diffErr = DiffConfigFile(oldFileName, testConfig{S1: "one", I2: 2, S3: "secret"})
+	requirer.NoError(diffErr)
+	requirer.Equal([]ConfigChange{{Name: "DIFFF_X", Kind: ChangeRemoved, OldVal: "extra"}}, changes)
+
+	// missing files have no entries
+	changes, diffErr = DiffConfigFile(filepath.Join(tempDir, "missing.env"), testConfig{S1: "one"})
+	requirer.NoError(diffErr)
+	requirer.Equal(3, len(changes))
+	requirer.Equal(ChangeAdded, changes[0].Kind)
+}
+
+func TestDiffConfigFileAsSaved(t *testing.T) {
+
+	type testConfig struct {
+		S1 string `env:"DIFFS_S1"`
+		S2 string `env:"DIFFS_S2" secret:"hide"`
+	}
+
+	requirer := require.New(t)
+	unsetTestEnv(t, "DIFFS_S1", "DIFFS_S2", DefaultProfileEnv)
+
+	tempDir := t.TempDir()
+	envFileName := filepath.Join(tempDir, "config.env")
+	store := &FileSecretStore{FileName: filepath.Join(tempDir, "secrets.env")}
+	config := testConfig{S1: "one", S2: "secret"}
+	requirer.NoError(SaveConfig(envFileName, config, WithSecretStore(store)))
+
+	// values kept in the secret store aren't compared with the file
+	changes, diffErr := DiffConfigFile(envFileName, config, WithSecretStore(store))
+	requirer.NoError(diffErr)
+	requirer.Empty(changes)
+
+	// the entries of the profile's file take precedence
+	requirer.NoError(os.WriteFile(ProfileFile(envFileName, "prod"), []byte("DIFFS_S1=prod\n"), 0600))
+	changes, diffErr = DiffConfigFile(envFileName, config, WithSecretStore(store), WithProfile("prod"))
+	requirer.NoError(diffErr)
+	requirer.Equal([]ConfigChange{{Name: "DIFFS_S1", Kind: ChangeModified, OldVal: "prod", NewVal: "one"}}, changes)
+}
@@ -2,7 +2,6 @@ package configurator
 
 import (
 	"fmt"
-	"log"
 	"reflect"
 	"strings"
 
@@ -10,34 +9,112 @@ import (
 )
 
 // EditConfig invokes a user dialog to present and optionally
-// change the current values in the 'config' structure
-func EditConfig[T any](config *T) error {
-	return editConfig(config, &promptUiSeamNoop{}, 100)
+// change the current values in the 'config' structure; given the
+// provenance recorded when loaded (see WithProvenance), the profile
+// (see WithProfile) from which each value came is shown
+func EditConfig[T any](config *T, opts ...Option) error {
+	return editConfig(config, &promptUiSeamNoop{}, 100, opts...)
+}
+
+// EditConfigMap invokes a user dialog to present and optionally change the
+// current values in 'configMap' (e.g., as read by ReadConfigMap), in order of
+// their names; entries having nil values aren't presented
+func EditConfigMap(configMap map[string]any, opts ...Option) error {
+	return editConfigMap(configMap, &promptUiSeamNoop{}, 100, opts...)
+}
+
+// EditConfigMapWithSchema invokes a user dialog to present and optionally change the
+// values in 'configMap' (e.g., as read by ReadConfigMap) of the entries described by
+// 'schema', in order of their names; values not valid according to 'schema' are rejected
+func EditConfigMapWithSchema(configMap map[string]any, schema *Schema, opts ...Option) error {
+	return editConfigMapWithSchema(configMap, schema, &promptUiSeamNoop{}, 100, opts...)
 }
 
 // editConfig provides a testable version of EditConfig
-func editConfig[T any](config *T, seam promptUiSeam, maxTimes int) error {
+func editConfig[T any](config *T, seam promptUiSeam, maxTimes int, opts ...Option) error {
+	getItems := func() ([]ConfigEnvItem, error) {
+		return GetConfigEnvItems(*config)
+	}
+	setItem := func(envName, newValueAsString string) error {
+		return SetConfigEnvItem(config, envName, newValueAsString, opts...)
+	}
+	return editItems(getItems, setItem, seam, maxTimes, newOptions(opts))
+}
+
+// editConfigMap provides a testable version of EditConfigMap
+func editConfigMap(configMap map[string]any, seam promptUiSeam, maxTimes int, opts ...Option) error {
+	getItems := func() ([]ConfigEnvItem, error) {
+		var items []ConfigEnvItem
+		for _, envName := range sortedKeys(configMap) {
+			if envVal := configMap[envName]; envVal != nil {
+				items = append(items, ConfigEnvItem{Name: envName, Val: envVal, Kind: reflect.TypeOf(envVal).Kind()})
+			}
+		}
+		return items, nil
+	}
+	setItem := func(envName, newValueAsString string) error {
+		configMap[envName] = newValueAsString
+		return nil
+	}
+	return editItems(getItems, setItem, seam, maxTimes, newOptions(opts))
+}
+
+// editConfigMapWithSchema provides a testable version of EditConfigMapWithSchema
+func editConfigMapWithSchema(configMap map[string]any, schema *Schema, seam promptUiSeam, maxTimes int, opts ...Option) error {
+	getItems := func() ([]ConfigEnvItem, error) {
+		return schema.ConfigEnvItems(configMap), nil
+	}
+	setItem := func(envName, newValueAsString string) error {
+		if validateErr := schema.ValidateValue(envName, newValueAsString); validateErr != nil {
+			return validateErr
+		}
+		configMap[envName] = schema.Properties[envName].configValue(newValueAsString)
+		return nil
+	}
+	return editItems(getItems, setItem, seam, maxTimes, newOptions(opts))
+}
+
+// editItems implements the user dialog presenting the items returned by 'getItems'
+// and changing them using 'setItem', until the user is done or 'maxTimes' is reached
+func editItems(getItems func() ([]ConfigEnvItem, error), setItem func(envName, newValueAsString string) error,
+	seam promptUiSeam, maxTimes int, options *options) error {
 
 	loopCounter := 0
 	for {
-		cfgTagItems, getterErr := GetConfigEnvItems(*config)
+		cfgTagItems, getterErr := getItems()
 		if getterErr != nil {
 			return getterErr
 		}
 
 		var promptErr error
 		for _, cti := range cfgTagItems {
+			label := cti.Name
+			if _, isShadowed := lookupExternalEnv(cti.Name); isShadowed {
+				// the value the user enters would be overridden by the environment when next loaded
+				label += " (shadowed by environment)"
+			}
+			if source := options.provenance[cti.Name]; source.Profile != "" {
+				// as loaded using WithProvenance
+				label += fmt.Sprintf(" (profile %s)", source.Profile)
+			}
 			var result string
-			if cti.Kind == reflect.Bool {
+			if len(cti.Options) != 0 {
 				prompt := promptui.Select{
-					Label:     cti.Name,
+					Label:     label,
+					Items:     cti.Options,
+					CursorPos: optionIndex(cti.Options, fmt.Sprintf("%v", cti.Val)),
+				}
+				_, result, promptErr = seam.getSelector(&prompt).Run()
+			} else if cti.Kind == reflect.Bool {
+				prompt := promptui.Select{
+					Label:     label,
 					Items:     []string{"False", "True"},
 					CursorPos: map[bool]int{false: 0, true: 1}[cti.Val == true],
 				}
 				_, result, promptErr = seam.getSelector(&prompt).Run()
 			} else {
 				prompt := promptui.Prompt{
-					Label:     cti.Name,
+					Label:     label,
 					Default:   fmt.Sprintf("%v", cti.Val),
 					AllowEdit: true,
 				}
@@ -54,8 +131,8 @@ func editConfig[T any](config *T, seam promptUiSeam, maxTimes int) error {
 				return promptErr
 			}
 
-			if setErr := SetConfigEnvItem(config, cti.Name, result); setErr != nil {
-				log.Printf("NOTE: while setting item: %v\n", setErr)
+			if setErr := setItem(cti.Name, result); setErr != nil {
+				options.logger.Warn("value not set", "env", cti.Name, "error", setErr)
 			}
 		}
 
@@ -80,6 +157,16 @@ func editConfig[T any](config *T, seam promptUiSeam, maxTimes int) error {
 	return nil
 }
 
+// optionIndex returns the index of 'value' within 'options', or 0 if not found
+func optionIndex(options []string, value string) int {
+	for i, option := range options {
+		if option == value {
+			return i
+		}
+	}
+	return 0
+}
+
 type promptRunner interface {
 	Run() (string, error)
 }
@@ -2,6 +2,7 @@ package configurator
 
 import (
 	"os"
+	"strings"
 	"testing"
 
 	"github.com/manifoldco/promptui"
@@ -65,6 +66,80 @@ func TestApiEdit(t *testing.T) {
 
 }
 
+func TestEditConfigMap(t *testing.T) {
+
+	requirer := require.New(t)
+
+	configMap := map[string]any{"B_KEY": "b value", "A_KEY": "a value", "GONE": nil}
+	seam := &promptUiTestSeam{
+		pr: mockPr{
+			mockedResponses: map[int]string{
+				0: "new a value",
+				2: "y",
+			},
+		},
+	}
+	requirer.NoError(editConfigMap(configMap, seam, 1))
+
+	requirer.Equal(3, len(seam.prompters))
+	prompt1 := seam.prompters[0].(*promptui.Prompt)
+	requirer.Equal("A_KEY", prompt1.Label)
+	requirer.Equal("a value", prompt1.Default)
+	requirer.Equal("B_KEY", seam.prompters[1].(*promptui.Prompt).Label)
+	requirer.Equal(map[string]any{"A_KEY": "new a value", "B_KEY": "mock prompt response", "GONE": nil}, configMap)
+}
+
+func TestEditEnum(t *testing.T) {
+
+	type testConfig struct {
+		Level string `env:"LEVEL" enum:"debug,info,warn"`
+	}
+
+	requirer := require.New(t)
+
+	config := testConfig{Level: "info"}
+	seam := &promptUiTestSeam{
+		pr: mockPr{mockedResponses: map[int]string{0: "y"}},
+		sr: mockSr{mockedResponses: map[int]string{0: "warn"}},
+	}
+	requirer.NoError(editConfig(&config, seam, 1))
+
+	prompt1 := seam.prompters[0].(*promptui.Select)
+	requirer.Equal("LEVEL", prompt1.Label)
+	requirer.Equal([]string{"debug", "info", "warn"}, prompt1.Items)
+	requirer.Equal(1, prompt1.CursorPos)
+	requirer.Equal("warn", config.Level)
+}
+
+func TestEditConfigMapWithSchema(t *testing.T) {
+
+	requirer := require.New(t)
+
+	schema, readErr := ReadSchema(strings.NewReader(`
+properties:
+  ES_DEBUG: {type: boolean}
+  ES_KEY: {type: string, x-secret: hide}
+  ES_PORT: {type: integer, default: 8080}
+`))
+	requirer.NoError(readErr)
+
+	configMap := map[string]any{"ES_PORT": "80", "OTHER": "unchanged"}
+	seam := &promptUiTestSeam{
+		pr: mockPr{mockedResponses: map[int]string{0: "shush", 1: "not a number", 2: "y"}},
+		sr: mockSr{mockedResponses: map[int]string{0: "True"}},
+	}
+	requirer.NoError(editConfigMapWithSchema(configMap, schema, seam, 1))
+
+	requirer.Equal(4, len(seam.prompters))
+	requirer.Equal("ES_DEBUG", seam.prompters[0].(*promptui.Select).Label)
+	prompt2 := seam.prompters[1].(*promptui.Prompt)
+	requirer.Equal("ES_KEY", prompt2.Label)
+	requirer.True(prompt2.HideEntered)
+	requirer.Equal("80", seam.prompters[2].(*promptui.Prompt).Default)
+	// the invalid port is rejected
+	requirer.Equal(map[string]any{"ES_DEBUG": true, "ES_KEY": "shush", "ES_PORT": "80", "OTHER": "unchanged"}, configMap)
+}
+
 type mockPr struct {
 	responseCount   int
 	mockedResponses map[int]string
@@ -119,6 +194,14 @@ func createTempEnvFileFromMap(t *testing.T, envMap map[string]any) (envFileName
 	defer func() { require.NoError(t, envFile.Close()) }()
 	envFileName = envFile.Name()
 	t.Cleanup(func() { require.NoError(t, os.Remove(envFileName)) })
-	err = updateConfigFromMap(envFile, envMap)
+	err = updateConfigFromMap(envFile, envMap, DotenvFormat, newOptions(nil))
 	return
 }
+
+// unsetTestEnv unsets the variables named 'envNames' for the duration of the test
+func unsetTestEnv(t *testing.T, envNames ...string) {
+	for _, envName := range envNames {
+		t.Setenv(envName, "")
+		require.NoError(t, os.Unsetenv(envName))
+	}
+}
@@ -0,0 +1,128 @@
+package configurator
+
+import (
+	"context"
+	"crypto/rand"
+	"encoding/base64"
+	"errors"
+	"fmt"
+	"strings"
+
+	"github.com/sethvargo/go-envconfig"
+)
+
+// encryptedValuePrefix identifies a configuration value holding an encrypted envelope
+const encryptedValuePrefix = "enc:v1:"
+
+// GenerateEncryptionKey returns a new random encryption key, encoded as expected
+// within a key file or environment variable (see WithEncryptionKeyFile)
+func GenerateEncryptionKey() (string, error) {
+	key := make([]byte, cryptoKeySize)
+	if _, readErr := rand.Read(key); readErr != nil {
+		return "", readErr
+	}
+	return base64.StdEncoding.EncodeToString(key), nil
+}
+
+// IsEncryptedValue reports whether 'value' is an encrypted envelope (e.g., "enc:v1:...")
+func IsEncryptedValue(value string) bool {
+	return strings.HasPrefix(value, encryptedValuePrefix)
+}
+
+// EncryptValue returns the encrypted envelope of 'value' using 'key', bound to the entry
+// 'envName' so that it can't be decrypted as the value of any other entry
+func EncryptValue(key []byte, envName, value string) (string, error) {
+	sealed, sealErr := seal(key, []byte(value), []byte(envName))
+	if sealErr != nil {
+		return "", sealErr
+	}
+	return encryptedValuePrefix + base64.StdEncoding.EncodeToString(sealed), nil
+}
+
+// DecryptValue returns the value within the encrypted envelope 'value' of the entry 'envName'
+// using 'key', or 'value' itself if it isn't an encrypted envelope
+func DecryptValue(key []byte, envName, value string) (string, error) {
+	encoded, isEncrypted := strings.CutPrefix(value, encryptedValuePrefix)
+	if !isEncrypted {
+		return value, nil
+	}
+	sealed, decodeErr := base64.StdEncoding.DecodeString(encoded)
+	if decodeErr != nil {
+		return "", fmt.Errorf("malformed encrypted value: %w", decodeErr)
+	}
+	plaintext, unsealErr := unseal(key, sealed, []byte(envName))
+	if unsealErr != nil {
+		return "", unsealErr
+	}
+	return string(plaintext), nil
+}
+
+// decodeEncryptionKey decodes a key in the format returned by GenerateEncryptionKey
+func decodeEncryptionKey(encodedKey string) ([]byte, error) {
+	key, decodeErr := base64.StdEncoding.DecodeString(strings.TrimSpace(encodedKey))
+	if decodeErr != nil {
+		return nil, fmt.Errorf("malformed encryption key: %w", decodeErr)
+	}
+	if len(key) != cryptoKeySize {
+		return nil, fmt.Errorf("malformed encryption key; has %d bytes, need %d", len(key), cryptoKeySize)
+	}
+	return key, nil
+}
+
+// getEncryptionKey returns the encryption key established by the options
+func (o *options) getEncryptionKey() ([]byte, error) {
+	if o.encryptionKey == nil {
+		return nil, errors.New("no encryption key was given")
+	}
+	return o.encryptionKey()
+}
+
+// encryptValue returns the encrypted envelope of 'value' of the entry 'envName' using the
+// encryption key established by the options
+func (o *options) encryptValue(envName, value string) (string, error) {
+	key, keyErr := o.getEncryptionKey()
+	if keyErr != nil {
+		return "", keyErr
+	}
+	return EncryptValue(key, envName, value)
+}
+
+// decryptMutator is an envconfig.MutatorFunc replacing encrypted envelopes with the values within them
+func (o *options) decryptMutator(_ context.Context, envName, envVal string) (string, error) {
+	return o.decryptValue(envName, envName, envVal)
+}
+
+// decryptMutatorFor returns an envconfig.MutatorFunc replacing encrypted envelopes with the values
+// within them, which may be bound to the name of the item or any of its deprecated aliases (see the
+// `aliases` tag) known to 'lookuper', e.g., when renamed by a migration (see RenameEntry)
+func (o *options) decryptMutatorFor(lookuper *recordingLookuper) envconfig.MutatorFunc {
+	return func(_ context.Context, envName, envVal string) (string, error) {
+		decrypted, decryptErr := o.decryptValue(envName, envName, envVal)
+		for _, alias := range lookuper.aliases[envName] {
+			if decryptErr == nil {
+				break
+			}
+			if aliasDecrypted, aliasErr := o.decryptValue(envName, alias, envVal); aliasErr == nil {
+				decrypted, decryptErr = aliasDecrypted, nil
+			}
+		}
+		return decrypted, decryptErr
+	}
+}
+
+// decryptValue returns the value within the encrypted envelope 'envVal' of the item 'envName',
+// bound to the entry 'boundName', or 'envVal' itself if it isn't an encrypted envelope
+func (o *options) decryptValue(envName, boundName, envVal string) (string, error) {
+	if !IsEncryptedValue(envVal) {
+		return envVal, nil
+	}
+	key, keyErr := o.getEncryptionKey()
+	if keyErr != nil {
+		return "", fmt.Errorf("can't decrypt(%s): %w", envName, keyErr)
+	}
+	decrypted, decryptErr := DecryptValue(key, boundName, envVal)
+	if decryptErr != nil {
+		return "", fmt.Errorf("can't decrypt(%s): %w", envName, decryptErr)
+	}
+	return decrypted, nil
+}
@@ -0,0 +1,133 @@
+package configurator
+
+import (
+	"fmt"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+
+	"github.com/stretchr/testify/require"
+)
+
+func TestEncryptDecryptValue(t *testing.T) {
+
+	requirer := require.New(t)
+
+	encodedKey, generateErr := GenerateEncryptionKey()
+	requirer.NoError(generateErr)
+	key, decodeErr := decodeEncryptionKey(encodedKey + "\n")
+	requirer.NoError(decodeErr)
+
+	encrypted, encryptErr := EncryptValue(key, "ENC_S", "forYourEyesOnly")
+	requirer.NoError(encryptErr)
+	requirer.True(IsEncryptedValue(encrypted))
+	requirer.NotContains(encrypted, "forYourEyesOnly")
+
+	decrypted, decryptErr := DecryptValue(key, "ENC_S", encrypted)
+	requirer.NoError(decryptErr)
+	requirer.Equal("forYourEyesOnly", decrypted)
+
+	// the envelope is bound to its entry
+	_, decryptErr = DecryptValue(key, "ENC_T", encrypted)
+	requirer.ErrorContains(decryptErr, "wrong key or corrupted value")
+
+	plain, plainErr := DecryptValue(key, "ENC_S", "not encrypted")
+	requirer.NoError(plainErr)
+	requirer.Equal("not encrypted", plain)
+
+	otherEncodedKey, _ := GenerateEncryptionKey()
+	otherKey, _ := decodeEncryptionKey(otherEncodedKey)
+	_, decryptErr = DecryptValue(otherKey, "ENC_S", encrypted)
+	requirer.ErrorContains(decryptErr, "wrong key")
+
+	_, decodeErr = decodeEncryptionKey("c2hvcnQ=")
+	requirer.ErrorContains(decodeErr, "has 5 bytes")
+}
+
+func TestApiSaveLoadEncrypted(t *testing.T) {
+
+	type testConfig struct {
+		S1 string `env:"ENC_S1"`
+		S2 string `env:"ENC_S2" secret:"mask"`
+	}
+
+	requirer := require.New(t)
+	t.Setenv("ENC_S1", "")
+	t.Setenv("ENC_S2", "")
+
+	encodedKey, generateErr := GenerateEncryptionKey()
+	requirer.NoError(generateErr)
+	keyFileName := filepath.Join(t.TempDir(), "config.key")
+	requirer.NoError(os.WriteFile(keyFileName, []byte(encodedKey+"\n"), 0600))
+
+	envFileName, ctefErr := createTempEnvFileFromMap(t, map[string]any{})
+	requirer.NoError(ctefErr)
+
+	// the secret is written as an encrypted envelope
+	requirer.NoError(SaveConfig(envFileName, testConfig{S1: "plain", S2: "3454"}, WithEncryptionKeyFile(keyFileName)))
+	contents, readErr := os.ReadFile(envFileName)
+	requirer.NoError(readErr)
+	requirer.True(strings.HasPrefix(string(contents), "ENC_S1=plain\nENC_S2=enc:v1:"), string(contents))
+	requirer.True(IsEncryptedValue(os.Getenv("ENC_S2")))
+	changes, diffErr := DiffConfigFile(envFileName, testConfig{S1: "plain", S2: "3454"}, WithEncryptionKeyFile(keyFileName))
+	requirer.NoError(diffErr)
+	requirer.Empty(changes)
+
+	// and transparently decrypted when loaded
+	config := testConfig{}
+	requirer.NoError(LoadConfig(envFileName, &config, WithEncryptionKeyFile(keyFileName)))
+	requirer.Equal(testConfig{S1: "plain", S2: "3454"}, config)
+
+	t.Setenv("ENC_KEY", encodedKey)
+	config = testConfig{}
+	requirer.NoError(LoadConfig(envFileName, &config, WithEncryptionKeyEnv("ENC_KEY")))
+	requirer.Equal("3454", config.S2)
+
+	// encrypted envelopes aren't loaded without the key
+	config = testConfig{}
+	requirer.ErrorContains(LoadConfig(envFileName, &config), "can't decrypt(ENC_S2)")
+
+	// nor when moved to another entry
+	requirer.NoError(os.Unsetenv("ENC_S1"))
+	requirer.NoError(os.Unsetenv("ENC_S2"))
+	fileEnv, readErr := readConfigFile(envFileName, DotenvFormat)
+	requirer.NoError(readErr)
+	swapped := fmt.Sprintf("ENC_S1=%s\nENC_S2=%s\n", fileEnv["ENC_S2"], fileEnv["ENC_S2"])
+	requirer.NoError(os.WriteFile(envFileName, []byte(swapped), 0600))
+	config = testConfig{}
+	loadErr := LoadConfig(envFileName, &config, WithEncryptionKeyFile(keyFileName))
+	requirer.ErrorContains(loadErr, "can't decrypt(ENC_S1)")
+	requirer.NotContains(loadErr.Error(), "ENC_S2")
+}
+
+func TestApiLoadEncryptedAlias(t *testing.T) {
+
+	type testConfig struct {
+		S string `env:"ENC_NEW" aliases:"ENC_OLD" secret:"mask"`
+	}
+
+	requirer := require.New(t)
+	unsetTestEnv(t, "ENC_NEW", "ENC_OLD")
+
+	key, decodeErr := decodeEncryptionKey(mustGenerateEncryptionKey(t))
+	requirer.NoError(decodeErr)
+	encrypted, encryptErr := EncryptValue(key, "ENC_OLD", "3454")
+	requirer.NoError(encryptErr)
+
+	// envelopes bound to deprecated aliases are decrypted, whether or not renamed
+	envFileName := filepath.Join(t.TempDir(), "config.env")
+	for _, entryName := range []string{"ENC_OLD", "ENC_NEW"} {
+		requirer.NoError(os.WriteFile(envFileName, []byte(entryName+"="+encrypted+"\n"), 0600))
+		config := testConfig{}
+		requirer.NoError(LoadConfig(envFileName, &config, WithEncryptionKey(key), WithLogger(nil)), entryName)
+		requirer.Equal("3454", config.S, entryName)
+		requirer.NoError(os.Unsetenv(entryName))
+	}
+}
+
+func mustGenerateEncryptionKey(t *testing.T) string {
+	encodedKey, generateErr := GenerateEncryptionKey()
+	require.NoError(t, generateErr)
+	return encodedKey
+}
@@ -0,0 +1,38 @@
+package configurator
+
+import (
+	"os"
+	"sync"
+)
+
+// managedEnv records the values configurator has set into the environment, by name
+var managedEnv sync.Map
+
+// setManagedEnv sets the environment variable 'envName', recording that configurator set it
+func setManagedEnv(envName, envVal string) error {
+	if setEnvErr := os.Setenv(envName, envVal); setEnvErr != nil {
+		return setEnvErr
+	}
+	managedEnv.Store(envName, envVal)
+	return nil
+}
+
+// unsetManagedEnv removes the environment variable 'envName'
+func unsetManagedEnv(envName string) error {
+	managedEnv.Delete(envName)
+	return os.Unsetenv(envName)
+}
+
+// lookupExternalEnv returns the value of the environment variable 'envName' if it was set
+// other than by configurator (e.g., exported by the user's shell), in which case it shadows
+// the value of the corresponding entry of the configuration file
+func lookupExternalEnv(envName string) (string, bool) {
+	envVal, found := os.LookupEnv(envName)
+	if !found {
+		return "", false
+	}
+	if managedVal, isManaged := managedEnv.Load(envName); isManaged && managedVal == envVal {
+		return "", false
+	}
+	return envVal, true
+}
@@ -0,0 +1,72 @@
+package configurator
+
+import (
+	"bytes"
+	"log/slog"
+	"os"
+	"testing"
+
+	"github.com/manifoldco/promptui"
+	"github.com/stretchr/testify/require"
+)
+
+func TestApiShadowedByEnv(t *testing.T) {
+
+	type testConfig struct {
+		S1 string `env:"SHADOW_S1"`
+		S2 string `env:"SHADOW_S2"`
+	}
+
+	requirer := require.New(t)
+
+	logBuffer := &bytes.Buffer{}
+	withLogger := WithLogger(slog.New(slog.NewTextHandler(logBuffer, &slog.HandlerOptions{
+		ReplaceAttr: func(_ []string, attr slog.Attr) slog.Attr {
+			if attr.Key == slog.TimeKey {
+				return slog.Attr{}
+			}
+			return attr
+		},
+	})))
+
+	envFileName, ctefErr := createTempEnvFileFromMap(t, map[string]any{"SHADOW_S1": "from file", "SHADOW_S2": "from file"})
+	requirer.NoError(ctefErr)
+	t.Setenv("SHADOW_S1", "exported")
+	unsetTestEnv(t, "SHADOW_S2")
+
+	// loading reports file entries shadowed by the environment
+	config := testConfig{}
+	requirer.NoError(LoadConfig(envFileName, &config, withLogger))
+	requirer.Equal(testConfig{S1: "exported", S2: "from file"}, config)
+	requirer.Equal(`level=WARN msg="value is shadowed by the environment" file=`+envFileName+" env=SHADOW_S1\n", logBuffer.String())
+	requirer.NotContains(logBuffer.String(), "SHADOW_S2")
+
+	// the editor flags them
+	seam := &promptUiTestSeam{pr: mockPr{mockedResponses: map[int]string{0: "edited", 1: "edited", 2: "y"}}}
+	requirer.NoError(editConfig(&config, seam, 1))
+	requirer.Equal("SHADOW_S1 (shadowed by environment)", seam.prompters[0].(*promptui.Prompt).Label)
+	requirer.Equal("SHADOW_S2", seam.prompters[1].(*promptui.Prompt).Label)
+
+	// saving reports them
+	logBuffer.Reset()
+	requirer.NoError(SaveConfig(envFileName, testConfig{S1: "saved", S2: "saved"}, withLogger))
+	requirer.Equal(`level=WARN msg="saved value is shadowed by the environment" file=`+envFileName+" env=SHADOW_S1\n", logBuffer.String())
+	requirer.NotContains(logBuffer.String(), "SHADOW_S2")
+
+	// values set by configurator don't shadow the file, even when it's changed
+	requirer.NoError(os.WriteFile(envFileName, []byte("SHADOW_S2=changed\n"), 0600))
+	t.Setenv("SHADOW_S1", "exported")
+	logBuffer.Reset()
+	config = testConfig{}
+	requirer.NoError(LoadConfig(envFileName, &config, withLogger))
+	requirer.Equal(testConfig{S1: "exported", S2: "changed"}, config)
+	requirer.Empty(logBuffer.String())
+
+	// the file can be made to override the environment
+	requirer.NoError(os.WriteFile(envFileName, []byte("SHADOW_S1=from file\n"), 0600))
+	config = testConfig{}
+	requirer.NoError(LoadConfig(envFileName, &config, WithFileOverridingEnv(), withLogger))
+	requirer.Equal(testConfig{S1: "from file", S2: "changed"}, config)
+	requirer.Equal("from file", os.Getenv("SHADOW_S1"))
+	requirer.Empty(logBuffer.String())
+}
@@ -0,0 +1,101 @@
+package configurator
+
+import (
+	"errors"
+	"fmt"
+	"strconv"
+	"strings"
+
+	"github.com/sethvargo/go-envconfig"
+)
+
+var (
+	// ErrNotFound indicates that no environment item has the given name
+	ErrNotFound = errors.New("not found")
+	// ErrMissingRequired indicates that no value was found for an item tagged as "required"
+	ErrMissingRequired = envconfig.ErrMissingRequired
+	// ErrInterpolationCycle indicates that the value of an entry refers to itself, directly or indirectly
+	ErrInterpolationCycle = errors.New("interpolation cycle")
+	// ErrIncludeCycle indicates that a configuration file includes itself, directly or indirectly
+	ErrIncludeCycle = errors.New("include cycle")
+)
+
+// FieldError reports a problem with the value of the environment item named EnvName
+type FieldError struct {
+	EnvName string
+	// Value is the offending value, if any; the values of secrets are redacted (see DisplayVal)
+	Value string
+	Err   error
+}
+
+func (fe *FieldError) Error() string {
+	if fe.Value != "" {
+		return fmt.Sprintf("invalid value(%q) for(%s): %v", fe.Value, fe.EnvName, fe.Err)
+	}
+	return fmt.Sprintf("invalid(%s): %v", fe.EnvName, fe.Err)
+}
+
+func (fe *FieldError) Unwrap() error {
+	return fe.Err
+}
+
+// newFieldError returns a FieldError for 'value' of the item named 'envName', redacting
+// the value if the item is secret (i.e., has the `secret` tag value 'secret'); since
+// strconv errors repeat the value, only their cause is kept
+func newFieldError(envName, value, secret string, err error) *FieldError {
+	var numErr *strconv.NumError
+	if errors.As(err, &numErr) {
+		err = numErr.Err
+	}
+	if value != "" {
+		value = fmt.Sprintf("%v", ConfigEnvItem{Val: value, Secret: secret}.DisplayVal())
+	}
+	return &FieldError{EnvName: envName, Value: value, Err: err}
+}
+
+// FileError reports a configuration file which couldn't be read (e.g., for lack of
+// permission) or parsed (e.g., due to a syntax error at Line); errors.Is(err, fs.ErrNotExist)
+// reports whether it's missing
+type FileError struct {
+	File string
+	// Line is the number of the line at which the file couldn't be parsed, if known
+	Line int
+	Err  error
+}
+
+func (fe *FileError) Error() string {
+	if fe.Line > 0 {
+		return fmt.Sprintf("can't read(%s:%d): %v", fe.File, fe.Line, fe.Err)
+	}
+	return fmt.Sprintf("can't read(%s): %v", fe.File, fe.Err)
+}
+
+func (fe *FileError) Unwrap() error {
+	return fe.Err
+}
+
+// MultiError reports all the problems found (e.g., a FieldError for each invalid item);
+// errors.Is and errors.As examine each of them
+type MultiError struct {
+	Errs []error
+}
+
+func (me *MultiError) Error() string {
+	errStrings := make([]string, 0, len(me.Errs))
+	for _, err := range me.Errs {
+		errStrings = append(errStrings, err.Error())
+	}
+	return strings.Join(errStrings, "\n")
+}
+
+func (me *MultiError) Unwrap() []error {
+	return me.Errs
+}
+
+// newMultiError returns a MultiError reporting 'errs', or nil if there are none
+func newMultiError(errs []error) error {
+	if len(errs) == 0 {
+		return nil
+	}
+	return &MultiError{Errs: errs}
+}
@@ -0,0 +1,85 @@
+package configurator
+
+import (
+	"bytes"
+	"errors"
+	"os"
+	"testing"
+
+	"github.com/stretchr/testify/require"
+)
+
+func TestApiLoadFieldErrors(t *testing.T) {
+
+	type testConfig struct {
+		I1 int     `env:"ERR_I1"`
+		S2 string  `env:"ERR_S2,required"`
+		F3 float64 `env:"ERR_F3,default=1.5"`
+		I4 int     `env:"ERR_I4" secret:"mask"`
+		B5 bool    `env:"ERR_B5"`
+	}
+
+	requirer := require.New(t)
+	unsetTestEnv(t, "ERR_I1", "ERR_S2", "ERR_F3", "ERR_I4", "ERR_B5")
+
+	envFileName, ctefErr := createTempEnvFileFromMap(t, map[string]any{"ERR_I1": "one", "ERR_I4": "1234x", "ERR_B5": "true"})
+	requirer.NoError(ctefErr)
+
+	config := testConfig{}
+	loadErr := LoadConfig(envFileName, &config)
+	requirer.EqualError(loadErr, `invalid value("one") for(ERR_I1): invalid syntax
+invalid(ERR_S2): missing required value: ERR_S2
+invalid value("*****") for(ERR_I4): invalid syntax`)
+	requirer.ErrorIs(loadErr, ErrMissingRequired)
+
+	var multiErr *MultiError
+	requirer.ErrorAs(loadErr, &multiErr)
+	requirer.Equal(3, len(multiErr.Errs))
+	var fieldErr *FieldError
+	requirer.ErrorAs(multiErr.Errs[2], &fieldErr)
+	requirer.Equal(&FieldError{EnvName: "ERR_I4", Value: "*****", Err: fieldErr.Err}, fieldErr)
+
+	// the configuration is loaded until the first invalid item, as before
+	requirer.Equal(testConfig{}, config)
+}
+
+func TestSetConfigEnvItemErrors(t *testing.T) {
+
+	type testConfig struct {
+		I1 int    `env:"I1"`
+		S2 string `env:"S2" secret:"hide"`
+		U3 uint8  `env:"U3" secret:"mask"`
+	}
+
+	requirer := require.New(t)
+
+	config := testConfig{}
+	setErr := SetConfigEnvItem(&config, "NONESUCH", "x")
+	requirer.ErrorIs(setErr, ErrNotFound)
+	requirer.EqualError(setErr, "invalid(NONESUCH): not found")
+
+	setErr = SetConfigEnvItem(&config, "I1", "one")
+	var fieldErr *FieldError
+	requirer.ErrorAs(setErr, &fieldErr)
+	requirer.Equal("I1", fieldErr.EnvName)
+	requirer.Equal("one", fieldErr.Value)
+	requirer.EqualError(setErr, `invalid value("one") for(I1): invalid syntax`)
+
+	requirer.EqualError(SetConfigEnvItem(&config, "U3", "256"), `invalid value("***") for(U3): value out of range`)
+}
+
+func TestUpdateConfigFromMapErrors(t *testing.T) {
+
+	requirer := require.New(t)
+	t.Setenv("UPD_OK", "")
+
+	updateErr := updateConfigFromMap(&bytes.Buffer{}, map[string]any{"UPD_OK": "ok", "BAD=NAME": "x", "": "y"}, DotenvFormat, newOptions(nil))
+	var multiErr *MultiError
+	requirer.True(errors.As(updateErr, &multiErr))
+	requirer.Equal(2, len(multiErr.Errs))
+	var fieldErr *FieldError
+	requirer.ErrorAs(multiErr.Errs[1], &fieldErr)
+	requirer.Equal("BAD=NAME", fieldErr.EnvName)
+	requirer.ErrorContains(updateErr, "invalid(BAD=NAME): can't set: ")
+	requirer.Equal("ok", os.Getenv("UPD_OK"))
+}
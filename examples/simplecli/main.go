@@ -3,7 +3,6 @@ package main
 import (
 	"flag"
 	"log"
-	"strings"
 
 	"github.com/noodnik2/configurator"
 )
@@ -49,7 +48,7 @@ func showConfig() {
 
 	log.Println()
 	log.Println("Structure:")
-	log.Printf("\t%#v\n", config)
+	log.Printf("\t%#v\n", configurator.Redacted(config))
 
 	configEnvItems, getConfigErr := configurator.GetConfigEnvItems(config)
 	if getConfigErr != nil {
@@ -59,19 +58,7 @@ func showConfig() {
 	log.Println()
 	log.Println("Items:")
 	for _, configEnvItem := range configEnvItems {
-		var val any
-		if configEnvItem.Secret == "" {
-			val = configEnvItem.Val
-		} else {
-			// dealing with these flags is currently the client's responsibility; encapsulating
-			// support for handling these within 'configurator' is under consideration.
-			if configEnvItem.Secret == "mask" {
-				val = strings.Repeat("*", len(configEnvItem.Val.(string)))
-			} else {
-				val = "<suppressed>"
-			}
-		}
-		log.Printf("\t%s: %v\n", configEnvItem.Name, val)
+		log.Printf("\t%s: %v\n", configEnvItem.Name, configEnvItem.DisplayVal())
 	}
 
 }
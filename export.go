@@ -0,0 +1,205 @@
+package configurator
+
+import (
+	"crypto/rand"
+	"encoding/base64"
+	"encoding/hex"
+	"fmt"
+	"io"
+	"strings"
+
+	"gopkg.in/yaml.v3"
+)
+
+// ExportEntry is a configuration entry to be rendered by an Exporter
+type ExportEntry struct {
+	Name   string
+	Value  string
+	Secret bool
+}
+
+// Exporter renders configuration entries in a format used to deploy applications
+type Exporter interface {
+	Export(w io.Writer, entries []ExportEntry) error
+}
+
+// ExportConfig renders the environment items of 'config' to 'w' using 'exporter'
+func ExportConfig[T any](w io.Writer, exporter Exporter, config T) error {
+	envItems, getterErr := GetConfigEnvItems(config)
+	if getterErr != nil {
+		return getterErr
+	}
+	entries := make([]ExportEntry, 0, len(envItems))
+	for _, envItem := range envItems {
+		entries = append(entries, ExportEntry{Name: envItem.Name, Value: fmt.Sprintf("%v", envItem.Val), Secret: envItem.IsSecret()})
+	}
+	return exporter.Export(w, entries)
+}
+
+// ExportConfigMap renders the entries of 'configMap' (as given to SaveConfigMap) to 'w' using
+// 'exporter', treating those named in 'secretNames' as secrets; entries with nil values are omitted
+func ExportConfigMap(w io.Writer, exporter Exporter, configMap map[string]any, secretNames ...string) error {
+	isSecret := make(map[string]bool, len(secretNames))
+	for _, secretName := range secretNames {
+		isSecret[secretName] = true
+	}
+	entries := make([]ExportEntry, 0, len(configMap))
+	for _, envName := range sortedKeys(configMap) {
+		if configMap[envName] == nil {
+			continue
+		}
+		entries = append(entries, ExportEntry{Name: envName, Value: fmt.Sprintf("%v", configMap[envName]), Secret: isSecret[envName]})
+	}
+	return exporter.Export(w, entries)
+}
+
+// ShellExporter renders a POSIX shell script exporting the configuration entries;
+// secret entries are omitted unless IncludeSecrets is set
+type ShellExporter struct {
+	IncludeSecrets bool
+}
+
+// Export implements Exporter
+func (se *ShellExporter) Export(w io.Writer, entries []ExportEntry) error {
+	return exportLines(w, entries, se.IncludeSecrets, func(entry ExportEntry) (string, error) {
+		return fmt.Sprintf("export %s='%s'\n", entry.Name, strings.ReplaceAll(entry.Value, "'", `'\''`)), nil
+	})
+}
+
+// systemdValueEscaper escapes the characters having special meaning within
+// a double-quoted value of a systemd EnvironmentFile
+var systemdValueEscaper = strings.NewReplacer(`\`, `\\`, `"`, `\"`, "$", `\$`, "`", "\\`")
+
+// SystemdExporter renders a systemd EnvironmentFile holding the configuration entries;
+// secret entries are omitted unless IncludeSecrets is set
+type SystemdExporter struct {
+	IncludeSecrets bool
+}
+
+// Export implements Exporter
+func (se *SystemdExporter) Export(w io.Writer, entries []ExportEntry) error {
+	return exportLines(w, entries, se.IncludeSecrets, func(entry ExportEntry) (string, error) {
+		return fmt.Sprintf("%s=\"%s\"\n", entry.Name, systemdValueEscaper.Replace(entry.Value)), nil
+	})
+}
+
+// DockerEnvFileExporter renders a file for use with Docker's "--env-file" option holding
+// the configuration entries; secret entries are omitted unless IncludeSecrets is set.
+// Since Docker doesn't interpret quotes or escapes, multi-line values can't be rendered.
+type DockerEnvFileExporter struct {
+	IncludeSecrets bool
+}
+
+// Export implements Exporter
+func (dee *DockerEnvFileExporter) Export(w io.Writer, entries []ExportEntry) error {
+	return exportLines(w, entries, dee.IncludeSecrets, func(entry ExportEntry) (string, error) {
+		if strings.ContainsAny(entry.Value, "\r\n") {
+			return "", fmt.Errorf("can't export(%s); docker env files don't support multi-line values", entry.Name)
+		}
+		return fmt.Sprintf("%s=%s\n", entry.Name, entry.Value), nil
+	})
+}
+
+// GitHubActionsExporter renders a GitHub Actions environment file (e.g., for appending to
+// $GITHUB_ENV) holding the configuration entries; secret entries are omitted unless
+// IncludeSecrets is set
+type GitHubActionsExporter struct {
+	IncludeSecrets bool
+}
+
+// Export implements Exporter
+func (gae *GitHubActionsExporter) Export(w io.Writer, entries []ExportEntry) error {
+	return exportLines(w, entries, gae.IncludeSecrets, func(entry ExportEntry) (string, error) {
+		if !strings.ContainsAny(entry.Value, "\r\n") {
+			return fmt.Sprintf("%s=%s\n", entry.Name, entry.Value), nil
+		}
+		delimiter, delimiterErr := newHeredocDelimiter(entry.Value)
+		if delimiterErr != nil {
+			return "", delimiterErr
+		}
+		return fmt.Sprintf("%s<<%s\n%s\n%s\n", entry.Name, delimiter, entry.Value, delimiter), nil
+	})
+}
+
+// KubernetesExporter renders Kubernetes manifests: a ConfigMap holding the configuration
+// entries which aren't secret, and a Secret holding those which are
+type KubernetesExporter struct {
+	// Name is the name given to the ConfigMap and Secret
+	Name string
+	// Namespace is the namespace of the ConfigMap and Secret, if not empty
+	Namespace string
+}
+
+// Export implements Exporter
+func (ke *KubernetesExporter) Export(w io.Writer, entries []ExportEntry) error {
+	configMapData := make(map[string]string)
+	secretData := make(map[string]string)
+	for _, entry := range entries {
+		if entry.Secret {
+			secretData[entry.Name] = base64.StdEncoding.EncodeToString([]byte(entry.Value))
+			continue
+		}
+		configMapData[entry.Name] = entry.Value
+	}
+
+	encoder := yaml.NewEncoder(w)
+	encoder.SetIndent(2)
+	if encodeErr := encoder.Encode(ke.manifest("ConfigMap", "", configMapData)); encodeErr != nil {
+		return encodeErr
+	}
+	if len(secretData) != 0 {
+		if encodeErr := encoder.Encode(ke.manifest("Secret", "Opaque", secretData)); encodeErr != nil {
+			return encodeErr
+		}
+	}
+	return encoder.Close()
+}
+
+// kubernetesManifest is the subset of a Kubernetes ConfigMap or Secret manifest rendered by KubernetesExporter
+type kubernetesManifest struct {
+	APIVersion string            `yaml:"apiVersion"`
+	Kind       string            `yaml:"kind"`
+	Metadata   map[string]string `yaml:"metadata"`
+	Type       string            `yaml:"type,omitempty"`
+	Data       map[string]string `yaml:"data"`
+}
+
+func (ke *KubernetesExporter) manifest(kind, manifestType string, data map[string]string) kubernetesManifest {
+	metadata := map[string]string{"name": ke.Name}
+	if ke.Namespace != "" {
+		metadata["namespace"] = ke.Namespace
+	}
+	return kubernetesManifest{APIVersion: "v1", Kind: kind, Metadata: metadata, Type: manifestType, Data: data}
+}
+
+// exportLines writes the line(s) rendered by 'render' for each of 'entries' to 'w',
+// omitting secret entries unless 'includeSecrets' is set
+func exportLines(w io.Writer, entries []ExportEntry, includeSecrets bool, render func(ExportEntry) (string, error)) error {
+	for _, entry := range entries {
+		if entry.Secret && !includeSecrets {
+			continue
+		}
+		rendered, renderErr := render(entry)
+		if renderErr != nil {
+			return renderErr
+		}
+		if _, writeErr := io.WriteString(w, rendered); writeErr != nil {
+			return writeErr
+		}
+	}
+	return nil
+}
+
+// newHeredocDelimiter returns a random delimiter which doesn't occur within 'value'
+func newHeredocDelimiter(value string) (string, error) {
+	for {
+		randomBytes := make([]byte, 8)
+		if _, readErr := rand.Read(randomBytes); readErr != nil {
+			return "", readErr
+		}
+		delimiter := "EOF_" + hex.EncodeToString(randomBytes)
+		if !strings.Contains(value, delimiter) {
+			return delimiter, nil
+		}
+	}
+}
@@ -0,0 +1,117 @@
+package configurator
+
+import (
+	"bytes"
+	"regexp"
+	"testing"
+
+	"github.com/stretchr/testify/require"
+)
+
+func TestExporters(t *testing.T) {
+
+	type testConfig struct {
+		S1 string `env:"EXP_S1"`
+		I2 int    `env:"EXP_I2"`
+		S3 string `env:"EXP_S3"`
+		S4 string `env:"EXP_S4" secret:"hide"`
+	}
+
+	config := testConfig{S1: `it's $HOME "quoted"`, I2: 42, S3: "two\nlines", S4: "forYourEyesOnly"}
+
+	testCases := []struct {
+		name           string
+		exporter       Exporter
+		expectedOutput string
+		expectedErr    string
+	}{
+		{
+			name:     "shell",
+			exporter: &ShellExporter{},
+			expectedOutput: `export EXP_S1='it'\''s $HOME "quoted"'
+export EXP_I2='42'
+export EXP_S3='two
+lines'
+`,
+		},
+		{
+			name:     "shell including secrets",
+			exporter: &ShellExporter{IncludeSecrets: true},
+			expectedOutput: `export EXP_S1='it'\''s $HOME "quoted"'
+export EXP_I2='42'
+export EXP_S3='two
+lines'
+export EXP_S4='forYourEyesOnly'
+`,
+		},
+		{
+			name:     "systemd",
+			exporter: &SystemdExporter{},
+			expectedOutput: `EXP_S1="it's \$HOME \"quoted\""
+EXP_I2="42"
+EXP_S3="two
+lines"
+`,
+		},
+		{
+			name:        "docker",
+			exporter:    &DockerEnvFileExporter{},
+			expectedErr: "can't export(EXP_S3)",
+		},
+		{
+			name:     "kubernetes",
+			exporter: &KubernetesExporter{Name: "app-config", Namespace: "apps"},
+			expectedOutput: `apiVersion: v1
+kind: ConfigMap
+metadata:
+  name: app-config
+  namespace: apps
+data:
+  EXP_I2: "42"
+  EXP_S1: it's $HOME "quoted"
+  EXP_S3: |-
+    two
+    lines
+---
+apiVersion: v1
+kind: Secret
+metadata:
+  name: app-config
+  namespace: apps
+type: Opaque
+data:
+  EXP_S4: Zm9yWW91ckV5ZXNPbmx5
+`,
+		},
+	}
+
+	for _, tc := range testCases {
+		t.Run(tc.name, func(t *testing.T) {
+			requirer := require.New(t)
+			writer := &bytes.Buffer{}
+			exportErr := ExportConfig(writer, tc.exporter, config)
+			if tc.expectedErr != "" {
+				requirer.ErrorContains(exportErr, tc.expectedErr)
+				return
+			}
+			requirer.NoError(exportErr)
+			requirer.Equal(tc.expectedOutput, writer.String())
+		})
+	}
+}
+
+func TestExportConfigMap(t *testing.T) {
+
+	requirer := require.New(t)
+	configMap := map[string]any{"EXP_B": "x=y", "EXP_A": 1, "EXP_N": nil, "EXP_M": "a\nb", "EXP_K": "key"}
+
+	writer := &bytes.Buffer{}
+	requirer.NoError(ExportConfigMap(writer, &DockerEnvFileExporter{}, configMap, "EXP_K", "EXP_M"))
+	requirer.Equal("EXP_A=1\nEXP_B=x=y\n", writer.String())
+
+	writer.Reset()
+	requirer.NoError(ExportConfigMap(writer, &GitHubActionsExporter{IncludeSecrets: true}, configMap, "EXP_K"))
+	delimiter := regexp.MustCompile("EXP_M<<(EOF_[0-9a-f]{16})\n").FindStringSubmatch(writer.String())
+	requirer.Len(delimiter, 2)
+	requirer.Equal("EXP_A=1\nEXP_B=x=y\nEXP_K=key\nEXP_M<<"+delimiter[1]+"\na\nb\n"+delimiter[1]+"\n", writer.String())
+}
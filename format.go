@@ -0,0 +1,343 @@
+package configurator
+
+import (
+	"bytes"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"path/filepath"
+	"reflect"
+	"regexp"
+	"sort"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/BurntSushi/toml"
+	"github.com/joho/godotenv"
+	"gopkg.in/yaml.v3"
+)
+
+// Format reads and writes configuration files of a particular file format.  Whatever the
+// format, configuration entries are keyed by the environment names used in the `env` tags
+// of the configuration structure; structured formats hold them as top-level keys.
+type Format interface {
+	// Read returns the configuration entries found in 'r', keyed by environment name
+	Read(r io.Reader) (map[string]string, error)
+	// Write writes the configuration entries in 'configMap' to 'w', omitting those with nil values
+	Write(w io.Writer, configMap map[string]any) error
+}
+
+// Supported configuration file formats
+var (
+	DotenvFormat Format = dotenvFormat{}
+	JSONFormat   Format = jsonFormat{}
+	YAMLFormat   Format = yamlFormat{}
+	TOMLFormat   Format = tomlFormat{}
+)
+
+// FormatForFile returns the Format indicated by the extension of 'fileName' (i.e., ".json",
+// ".yaml", ".yml" or ".toml"), or DotenvFormat if the extension doesn't indicate one
+func FormatForFile(fileName string) Format {
+	switch strings.ToLower(filepath.Ext(fileName)) {
+	case ".json":
+		return JSONFormat
+	case ".yaml", ".yml":
+		return YAMLFormat
+	case ".toml":
+		return TOMLFormat
+	default:
+		return DotenvFormat
+	}
+}
+
+// formatFor returns the Format of 'fileName' established by the options
+func (o *options) formatFor(fileName string) Format {
+	if o.format != nil {
+		return o.format
+	}
+	return FormatForFile(fileName)
+}
+
+type dotenvFormat struct{}
+
+// dotenvReferenceHider hides references to variables from godotenv, which would otherwise expand
+// them, so that they're read as written (see interpolate); dotenvReferenceRevealer restores them
+var (
+	dotenvReferenceHider    = strings.NewReplacer(`\$`, "\uE001", "$", "\uE000")
+	dotenvReferenceRevealer = strings.NewReplacer("\uE001", `\$`, "\uE000", "$")
+)
+
+func (dotenvFormat) Read(r io.Reader) (map[string]string, error) {
+	contents, readErr := io.ReadAll(r)
+	if readErr != nil {
+		return nil, readErr
+	}
+	hiddenContents := []byte(dotenvReferenceHider.Replace(string(contents)))
+	envMap, parseErr := godotenv.UnmarshalBytes(hiddenContents)
+	if parseErr != nil {
+		return nil, newDotenvSyntaxError(hiddenContents, parseErr)
+	}
+	for envName, envVal := range envMap {
+		envMap[envName] = dotenvReferenceRevealer.Replace(envVal)
+	}
+	return envMap, nil
+}
+
+// syntaxError reports a problem found while parsing the contents of a configuration file at line
+type syntaxError struct {
+	line int
+	err  error
+}
+
+func (se *syntaxError) Error() string {
+	return fmt.Sprintf("line %d: %v", se.line, se.err)
+}
+
+func (se *syntaxError) Unwrap() error {
+	return se.err
+}
+
+var (
+	dotenvBadNameRegexp      = regexp.MustCompile(`(?s)^(unexpected character ".*" in variable name) near (".*")$`)
+	dotenvUnterminatedPrefix = "unterminated quoted value "
+	yamlLineRegexp           = regexp.MustCompile(`^yaml: line (\d+):`)
+)
+
+// newDotenvSyntaxError returns a syntaxError locating 'parseErr' (as returned by godotenv
+// for 'contents') within the contents; since godotenv quotes the remaining contents, which
+// may hold secrets, only its description of the problem is kept
+func newDotenvSyntaxError(contents []byte, parseErr error) error {
+	contents = bytes.ReplaceAll(contents, []byte("\r\n"), []byte("\n"))
+	offset, cause := -1, parseErr
+	if matches := dotenvBadNameRegexp.FindStringSubmatch(parseErr.Error()); matches != nil {
+		if near, unquoteErr := strconv.Unquote(matches[2]); unquoteErr == nil {
+			offset = len(contents) - len(near)
+		}
+		cause = errors.New(dotenvReferenceRevealer.Replace(matches[1]))
+	} else if fragment, found := strings.CutPrefix(parseErr.Error(), dotenvUnterminatedPrefix); found {
+		offset = bytes.LastIndex(contents, []byte(fragment))
+		cause = errors.New(strings.TrimSpace(dotenvUnterminatedPrefix))
+	}
+	if offset < 0 {
+		return parseErr
+	}
+	return &syntaxError{line: lineAt(contents, int64(offset)), err: cause}
+}
+
+// locateParseError returns the number of the line of 'contents' at which the problem
+// reported by 'parseErr' was found (or 0 if it's unknown), together with its cause
+func locateParseError(contents []byte, parseErr error) (int, error) {
+	var se *syntaxError
+	if errors.As(parseErr, &se) {
+		return se.line, se.err
+	}
+	var jsonSyntaxErr *json.SyntaxError
+	if errors.As(parseErr, &jsonSyntaxErr) {
+		return lineAt(contents, jsonSyntaxErr.Offset), parseErr
+	}
+	var jsonTypeErr *json.UnmarshalTypeError
+	if errors.As(parseErr, &jsonTypeErr) {
+		return lineAt(contents, jsonTypeErr.Offset), parseErr
+	}
+	var tomlErr toml.ParseError
+	if errors.As(parseErr, &tomlErr) {
+		return tomlErr.Position.Line, parseErr
+	}
+	if matches := yamlLineRegexp.FindStringSubmatch(parseErr.Error()); matches != nil {
+		line, _ := strconv.Atoi(matches[1])
+		return line, parseErr
+	}
+	return 0, parseErr
+}
+
+// lineAt returns the number of the line of 'contents' holding the byte at 'offset'
+func lineAt(contents []byte, offset int64) int {
+	offset = min(max(offset, 0), int64(len(contents)))
+	return bytes.Count(contents[:offset], []byte("\n")) + 1
+}
+
+func (dotenvFormat) Write(w io.Writer, configMap map[string]any) error {
+	for _, envVarName := range sortedKeys(configMap) {
+		envVal := configMap[envVarName]
+		if envVal == nil {
+			continue
+		}
+		quotedVal, quoteErr := quoteDotenvValue(fmt.Sprintf("%v", envVal))
+		if quoteErr != nil {
+			return fmt.Errorf("can't write(%s): %w", envVarName, quoteErr)
+		}
+		if _, printErr := fmt.Fprintf(w, "%s=%s\n", envVarName, quotedVal); printErr != nil {
+			return printErr
+		}
+	}
+	return nil
+}
+
+// quoteDotenvValue returns 'envVal' as written into a dotenv file such that it's read as is:
+// quoted if it has surrounding white space or characters having special meaning (e.g., starting
+// a comment or a new line), otherwise unchanged.  Double quotes (with escapes) are used unless
+// the value ends with a character godotenv would take as escaping the closing quote, in which case
+// single quotes are used unless the value holds a reference to a variable, which they'd make literal
+// (see templatesOf).  References aren't escaped, so that they're read as written.
+func quoteDotenvValue(envVal string) (string, error) {
+	if strings.TrimSpace(envVal) == envVal && !strings.ContainsAny(envVal, "#\n\r") && !strings.ContainsAny(envVal[:min(1, len(envVal))], "\"'`") {
+		return envVal, nil
+	}
+	if !strings.HasSuffix(envVal, `"`) && !strings.HasSuffix(envVal, `\`) {
+		return `"` + dotenvQuotedValueEscaper.Replace(envVal) + `"`, nil
+	}
+	if !strings.ContainsAny(envVal, "'\n\r$") && !strings.HasSuffix(envVal, `\`) {
+		return "'" + envVal + "'", nil
+	}
+	return "", errors.New("value can't be written in dotenv format")
+}
+
+// dotenvQuotedValueEscaper escapes the characters of a value written within double
+// quotes into a dotenv file (see quoteDotenvValue)
+var dotenvQuotedValueEscaper = strings.NewReplacer(`\`, `\\`, "\n", `\n`, "\r", `\r`, `"`, `\"`)
+
+// dotenvValueEscaper escapes the characters having special meaning within
+// a double-quoted dotenv value
+var dotenvValueEscaper = strings.NewReplacer(
+	`\`, `\\`, "\n", `\n`, "\r", `\r`, `"`, `\"`, "!", `\!`, "$", `\$`, "`", "\\`",
+)
+
+// marshalDotenv renders 'envMap' as sorted dotenv lines in the format NAME="VALUE", where
+// VALUE is escaped such that parsing it yields exactly the original value
+func marshalDotenv(envMap map[string]string) string {
+	lines := make([]string, 0, len(envMap))
+	for envName, envVal := range envMap {
+		lines = append(lines, fmt.Sprintf(`%s="%s"`, envName, dotenvValueEscaper.Replace(envVal))+"\n")
+	}
+	sort.Strings(lines)
+	return strings.Join(lines, "")
+}
+
+type jsonFormat struct{}
+
+func (jsonFormat) Read(r io.Reader) (map[string]string, error) {
+	decoder := json.NewDecoder(r)
+	decoder.UseNumber()
+	var structured map[string]any
+	if decodeErr := decoder.Decode(&structured); decodeErr != nil && decodeErr != io.EOF {
+		return nil, decodeErr
+	}
+	return flattenStructured(structured)
+}
+
+func (jsonFormat) Write(w io.Writer, configMap map[string]any) error {
+	encoder := json.NewEncoder(w)
+	encoder.SetIndent("", "  ")
+	return encoder.Encode(toStructured(configMap))
+}
+
+type yamlFormat struct{}
+
+func (yamlFormat) Read(r io.Reader) (map[string]string, error) {
+	var structured map[string]any
+	if decodeErr := yaml.NewDecoder(r).Decode(&structured); decodeErr != nil && decodeErr != io.EOF {
+		return nil, decodeErr
+	}
+	return flattenStructured(structured)
+}
+
+func (yamlFormat) Write(w io.Writer, configMap map[string]any) error {
+	encoder := yaml.NewEncoder(w)
+	encoder.SetIndent(2)
+	if encodeErr := encoder.Encode(toStructured(configMap)); encodeErr != nil {
+		return encodeErr
+	}
+	return encoder.Close()
+}
+
+type tomlFormat struct{}
+
+func (tomlFormat) Read(r io.Reader) (map[string]string, error) {
+	var structured map[string]any
+	if _, decodeErr := toml.NewDecoder(r).Decode(&structured); decodeErr != nil {
+		return nil, decodeErr
+	}
+	return flattenStructured(structured)
+}
+
+func (tomlFormat) Write(w io.Writer, configMap map[string]any) error {
+	return toml.NewEncoder(w).Encode(toStructured(configMap))
+}
+
+// flattenStructured converts the top-level entries of a decoded structured file into
+// configuration entries; lists of values are joined by commas, as expected by envconfig
+func flattenStructured(structured map[string]any) (map[string]string, error) {
+	configEntries := make(map[string]string, len(structured))
+	for envName, structuredVal := range structured {
+		if structuredVal == nil {
+			continue
+		}
+		if structuredList, isList := structuredVal.([]any); isList {
+			listEntries := make([]string, 0, len(structuredList))
+			for _, structuredListVal := range structuredList {
+				listEntry, convertErr := structuredScalarString(envName, structuredListVal)
+				if convertErr != nil {
+					return nil, convertErr
+				}
+				listEntries = append(listEntries, listEntry)
+			}
+			configEntries[envName] = strings.Join(listEntries, ",")
+			continue
+		}
+		configEntry, convertErr := structuredScalarString(envName, structuredVal)
+		if convertErr != nil {
+			return nil, convertErr
+		}
+		configEntries[envName] = configEntry
+	}
+	return configEntries, nil
+}
+
+// structuredScalarString returns the configuration value of a scalar read from a structured file
+func structuredScalarString(envName string, structuredVal any) (string, error) {
+	switch scalar := structuredVal.(type) {
+	case time.Time:
+		return scalar.Format(time.RFC3339Nano), nil
+	case string, bool, json.Number, int, int64, uint64, float64:
+		return fmt.Sprintf("%v", scalar), nil
+	default:
+		return "", fmt.Errorf("unsupported value for(%s) of type(%T)", envName, structuredVal)
+	}
+}
+
+// toStructured returns the entries of 'configMap' having non-nil values, converting values
+// which aren't of a predeclared basic type (e.g., time.Duration) to strings, so that they're
+// encoded as they'd be in a dotenv file
+func toStructured(configMap map[string]any) map[string]any {
+	structured := make(map[string]any, len(configMap))
+	for envName, envVal := range configMap {
+		if envVal == nil {
+			continue
+		}
+		if reflect.TypeOf(envVal).PkgPath() != "" {
+			structured[envName] = fmt.Sprintf("%v", envVal)
+			continue
+		}
+		switch reflect.ValueOf(envVal).Kind() {
+		case reflect.String, reflect.Bool, reflect.Float64, reflect.Float32,
+			reflect.Int, reflect.Int64, reflect.Int32, reflect.Int16, reflect.Int8,
+			reflect.Uint, reflect.Uint64, reflect.Uint32, reflect.Uint16, reflect.Uint8:
+			structured[envName] = envVal
+		default:
+			structured[envName] = fmt.Sprintf("%v", envVal)
+		}
+	}
+	return structured
+}
+
+// sortedKeys returns the keys of 'm' in sorted order
+func sortedKeys[V any](m map[string]V) []string {
+	keys := make([]string, 0, len(m))
+	for key := range m {
+		keys = append(keys, key)
+	}
+	sort.Strings(keys)
+	return keys
+}
@@ -0,0 +1,209 @@
+package configurator
+
+import (
+	"bytes"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/require"
+)
+
+func TestFormats(t *testing.T) {
+
+	configMap := map[string]any{
+		"FMT_S1": "it's \"quoted\"",
+		"FMT_F2": float32(3.14),
+		"FMT_B3": true,
+		"FMT_I4": -42,
+		"FMT_D5": 90 * time.Second,
+		"FMT_N6": nil,
+	}
+	expectedEntries := map[string]string{
+		"FMT_S1": "it's \"quoted\"",
+		"FMT_F2": "3.14",
+		"FMT_B3": "true",
+		"FMT_I4": "-42",
+		"FMT_D5": "1m30s",
+	}
+
+	testCases := []struct {
+		fileName       string
+		format         Format
+		expectedOutput string
+	}{
+		{
+			fileName:       "config.env",
+			format:         DotenvFormat,
+			expectedOutput: "FMT_B3=true\nFMT_D5=1m30s\nFMT_F2=3.14\nFMT_I4=-42\nFMT_S1=it's \"quoted\"\n",
+		},
+		{
+			fileName: "config.json",
+			format:   JSONFormat,
+			expectedOutput: `{
+  "FMT_B3": true,
+  "FMT_D5": "1m30s",
+  "FMT_F2": 3.14,
+  "FMT_I4": -42,
+  "FMT_S1": "it's \"quoted\""
+}
+`,
+		},
+		{
+			fileName: "config.YML",
+			format:   YAMLFormat,
+			expectedOutput: `FMT_B3: true
+FMT_D5: 1m30s
+FMT_F2: 3.14
+FMT_I4: -42
+FMT_S1: it's "quoted"
+`,
+		},
+		{
+			fileName: "config.toml",
+			format:   TOMLFormat,
+			expectedOutput: `FMT_B3 = true
+FMT_D5 = "1m30s"
+FMT_F2 = 3.14
+FMT_I4 = -42
+FMT_S1 = "it's \"quoted\""
+`,
+		},
+	}
+
+	for _, tc := range testCases {
+		t.Run(tc.fileName, func(t *testing.T) {
+			requirer := require.New(t)
+			requirer.Equal(tc.format, FormatForFile(tc.fileName))
+
+			writer := &bytes.Buffer{}
+			requirer.NoError(tc.format.Write(writer, configMap))
+			requirer.Equal(tc.expectedOutput, writer.String())
+
+			entries, readErr := tc.format.Read(strings.NewReader(writer.String()))
+			requirer.NoError(readErr)
+			requirer.Equal(expectedEntries, entries)
+		})
+	}
+}
+
+func TestDotenvWriteQuoting(t *testing.T) {
+
+	testCases := []struct {
+		name          string
+		envVal        string
+		expectedEntry string
+		expectedErr   string
+	}{
+		{name: "plain", envVal: "a value=1", expectedEntry: "K=a value=1\n"},
+		{name: "empty", envVal: "", expectedEntry: "K=\n"},
+		{name: "inner quotes", envVal: `a "b" 'c'`, expectedEntry: `K=a "b" 'c'` + "\n"},
+		{name: "backslashes", envVal: `C:\temp\new\`, expectedEntry: `K=C:\temp\new\` + "\n"},
+		{name: "comment", envVal: "a # b", expectedEntry: "K=\"a # b\"\n"},
+		{name: "padded", envVal: "  padded  ", expectedEntry: "K=\"  padded  \"\n"},
+		{name: "single quoted", envVal: "'quoted'", expectedEntry: "K=\"'quoted'\"\n"},
+		{name: "double quoted", envVal: `"quoted"`, expectedEntry: `K='"quoted"'` + "\n"},
+		{name: "backquoted", envVal: "`cmd`", expectedEntry: "K=\"`cmd`\"\n"},
+		{name: "new line", envVal: "x\nINJECTED=evil", expectedEntry: `K="x\nINJECTED=evil"` + "\n"},
+		{name: "carriage return", envVal: "x\r\ny", expectedEntry: `K="x\r\ny"` + "\n"},
+		{name: "escapes", envVal: ` \n"\x`, expectedEntry: `K=" \\n\"\\x"` + "\n"},
+		{name: "reference", envVal: "${HOME}/x$Y", expectedEntry: "K=${HOME}/x$Y\n"},
+		{name: "escaped reference", envVal: ` \${HOME}`, expectedEntry: `K=" \\${HOME}"` + "\n"},
+		{name: "quoted reference", envVal: `"${HOME}"`, expectedErr: "can't write(K): value can't be written in dotenv format"},
+		{name: "unwritable", envVal: " x\\", expectedErr: "can't write(K): value can't be written in dotenv format"},
+	}
+	for _, testCase := range testCases {
+		t.Run(testCase.name, func(t *testing.T) {
+			requirer := require.New(t)
+			written := &bytes.Buffer{}
+			writeErr := DotenvFormat.Write(written, map[string]any{"K": testCase.envVal})
+			if testCase.expectedErr != "" {
+				requirer.EqualError(writeErr, testCase.expectedErr)
+				return
+			}
+			requirer.NoError(writeErr)
+			requirer.Equal(testCase.expectedEntry, written.String())
+			readEnv, readErr := DotenvFormat.Read(written)
+			requirer.NoError(readErr)
+			requirer.Equal(map[string]string{"K": testCase.envVal}, readEnv)
+		})
+	}
+}
+
+func TestStructuredFormatRead(t *testing.T) {
+
+	requirer := require.New(t)
+
+	entries, readErr := YAMLFormat.Read(strings.NewReader("LIST: [a, 2, true]\nNOTHING:\nWHEN: 2023-07-04T12:00:00Z\n"))
+	requirer.NoError(readErr)
+	requirer.Equal(map[string]string{"LIST": "a,2,true", "WHEN": "2023-07-04T12:00:00Z"}, entries)
+
+	entries, readErr = JSONFormat.Read(strings.NewReader(""))
+	requirer.NoError(readErr)
+	requirer.Empty(entries)
+
+	_, readErr = JSONFormat.Read(strings.NewReader(`{"NESTED": {"KEY": "value"}}`))
+	requirer.ErrorContains(readErr, "unsupported value for(NESTED)")
+}
+
+func TestLocateParseError(t *testing.T) {
+
+	testCases := []struct {
+		name          string
+		format        Format
+		contents      string
+		expectedLine  int
+		expectedCause string
+	}{
+		{"dotenv name", DotenvFormat, "A=1\r\nB=2\r\nBAD-NAME=secret\r\n", 3, "unexpected character \"-\" in variable name"},
+		{"dotenv quote", DotenvFormat, "A=1\n\n# comment\nB=\"secret\n", 4, "unterminated quoted value"},
+		{"json", JSONFormat, "{\n  \"A\": \"1\",\n  \"B\" 2\n}", 3, "invalid character"},
+		{"yaml", YAMLFormat, "A: 1\nB: x: y\n", 2, "mapping values are not allowed"},
+		{"toml", TOMLFormat, "A = 1\nB = \n", 2, "expected value"},
+	}
+	for _, testCase := range testCases {
+		t.Run(testCase.name, func(t *testing.T) {
+			requirer := require.New(t)
+			_, readErr := testCase.format.Read(strings.NewReader(testCase.contents))
+			requirer.Error(readErr)
+			line, cause := locateParseError([]byte(testCase.contents), readErr)
+			requirer.Equal(testCase.expectedLine, line)
+			requirer.ErrorContains(cause, testCase.expectedCause)
+			requirer.NotContains(cause.Error(), "secret")
+		})
+	}
+}
+
+func TestApiSaveLoadWithFormat(t *testing.T) {
+
+	type testConfig struct {
+		S1 string  `env:"FMTAPI_S1"`
+		F2 float64 `env:"FMTAPI_F2,default=2.5"`
+	}
+
+	requirer := require.New(t)
+	unsetTestEnv(t, "FMTAPI_S1", "FMTAPI_F2")
+
+	// format indicated by the file's extension
+	yamlFileName := filepath.Join(t.TempDir(), "config.yaml")
+	requirer.NoError(os.WriteFile(yamlFileName, []byte("FMTAPI_S1: from yaml\n"), 0600))
+	config := testConfig{}
+	requirer.NoError(LoadConfig(yamlFileName, &config))
+	requirer.Equal(testConfig{S1: "from yaml", F2: 2.5}, config)
+	requirer.NoError(os.Unsetenv("FMTAPI_S1"))
+
+	// format given explicitly
+	jsonFileName := filepath.Join(t.TempDir(), "config")
+	requirer.NoError(SaveConfig(jsonFileName, testConfig{S1: "from json", F2: 1}, WithFormat(JSONFormat)))
+	contents, readErr := os.ReadFile(jsonFileName)
+	requirer.NoError(readErr)
+	requirer.Equal("{\n  \"FMTAPI_F2\": 1,\n  \"FMTAPI_S1\": \"from json\"\n}\n", string(contents))
+	requirer.NoError(os.Unsetenv("FMTAPI_S1"))
+	requirer.NoError(os.Unsetenv("FMTAPI_F2"))
+
+	config = testConfig{}
+	requirer.NoError(LoadConfig(jsonFileName, &config, WithFormat(JSONFormat)))
+	requirer.Equal(testConfig{S1: "from json", F2: 1}, config)
+}
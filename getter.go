@@ -12,9 +12,27 @@ type ConfigEnvItem struct {
 	Val    any
 	Secret string
 	Kind   reflect.Kind
+	// Default is the value given by the "default=" option of the item's `env` tag
+	Default string
+	// Required is set by the "required" option of the item's `env` tag
+	Required bool
+	// Description is given by the item's `desc` tag
+	Description string
+	// Options are the allowed values, given (comma separated) by the item's `enum` tag
+	Options []string
+	// Aliases are the deprecated names of the item, given (comma separated) by its `aliases` tag
+	Aliases []string
+	// Helper is the credential helper command (e.g., "pass show app/key") given by the item's `helper` tag
+	Helper string
 }
 
-const envTagKey = "env"
+const (
+	envTagKey     = "env"
+	descTagKey    = "desc"
+	enumTagKey    = "enum"
+	aliasesTagKey = "aliases"
+	helperTagKey  = "helper"
+)
 
 // GetConfigEnvItems gets a list of 'ConfigEnvItem' values from 'config'
 // elements tagged as environment items.  See https://go.dev/blog/laws-of-reflection
@@ -47,17 +65,54 @@ func GetConfigEnvItems[T any](config T) ([]ConfigEnvItem, error) {
 		}
 
 		envItem := ConfigEnvItem{Name: tagParts[0], Kind: cfgStructFieldElement.Kind()}
+		envItem.Default, envItem.Required = parseEnvTagOptions(tagParts[1:])
 		if secretTagVal, okS := cfgStructFieldTag.Lookup("secret"); okS {
 			envItem.Secret = secretTagVal
 		}
+		envItem.Description = cfgStructFieldTag.Get(descTagKey)
+		if enumTagVal := cfgStructFieldTag.Get(enumTagKey); enumTagVal != "" {
+			for _, option := range strings.Split(enumTagVal, ",") {
+				envItem.Options = append(envItem.Options, strings.TrimSpace(option))
+			}
+		}
+		envItem.Aliases = getAliases(cfgStructFieldTag)
+		envItem.Helper = strings.TrimSpace(cfgStructFieldTag.Get(helperTagKey))
 
-		envItem.Val = reflect.ValueOf(cfgStructFieldElement.Interface()).Interface()
+		// nil for an interface field holding nil
+		envItem.Val = cfgStructFieldElement.Interface()
 		cfgTagItems = append(cfgTagItems, envItem)
 	}
 
 	return cfgTagItems, nil
 }
 
+// getAliases returns the deprecated names given by the `aliases` tag within 'tag'
+func getAliases(tag reflect.StructTag) []string {
+	var aliases []string
+	for _, alias := range strings.Split(tag.Get(aliasesTagKey), ",") {
+		if alias = strings.TrimSpace(alias); alias != "" {
+			aliases = append(aliases, alias)
+		}
+	}
+	return aliases
+}
+
+// parseEnvTagOptions returns the default value and required flag given by the options
+// following the name in an `env` tag; as in envconfig, everything following "default="
+// (including commas) is the default value
+func parseEnvTagOptions(tagOptions []string) (defaultVal string, required bool) {
+	for i, tagOption := range tagOptions {
+		tagOption = strings.TrimSpace(tagOption)
+		switch {
+		case tagOption == "required":
+			required = true
+		case strings.HasPrefix(tagOption, "default="):
+			return strings.TrimPrefix(strings.TrimLeft(strings.Join(tagOptions[i:], ","), " "), "default="), required
+		}
+	}
+	return "", required
+}
+
 func getConfigStructInfo[T any](config *T) (reflect.Type, reflect.Value, error) {
 	cfgStructType := reflect.TypeOf(*config)
 	cfgStructElements := reflect.ValueOf(config).Elem()
@@ -1,6 +1,7 @@
 package configurator
 
 import (
+	"reflect"
 	"testing"
 
 	"github.com/stretchr/testify/require"
@@ -33,6 +34,19 @@ func TestGetConfigEnvItems(t *testing.T) {
 				requirer.Equal("f4", items[0].Name)
 			},
 		},
+		{
+			name: "defaults, required, descriptions and options",
+			config: struct {
+				F1 string `env:"F1,required,default=a, b" desc:"the first" enum:"a, b,c"`
+				F2 int    `env:"F2"`
+			}{},
+			assertions: func(requirer *require.Assertions, items []ConfigEnvItem) {
+				requirer.Equal(2, len(items))
+				requirer.Equal(ConfigEnvItem{Name: "F1", Val: "", Kind: reflect.String, Default: "a, b", Required: true,
+					Description: "the first", Options: []string{"a", "b", "c"}}, items[0])
+				requirer.Equal(ConfigEnvItem{Name: "F2", Val: 0, Kind: reflect.Int}, items[1])
+			},
+		},
 	}
 
 	for _, tc := range testCases {
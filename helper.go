@@ -0,0 +1,132 @@
+package configurator
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"os"
+	"os/exec"
+	"regexp"
+	"strings"
+	"time"
+)
+
+// DefaultHelperTimeout limits how long a credential helper command may run, unless
+// changed using WithHelperTimeout
+const DefaultHelperTimeout = 10 * time.Second
+
+// helperCommandRegexp matches an entry of a configuration file whose value is to be
+// obtained by running a credential helper command, e.g., "$(pass show app/key)"
+var helperCommandRegexp = regexp.MustCompile(`^\$\((.+)\)$`)
+
+// helperCommandOf returns the credential helper command named by the value 'envVal' of an
+// entry of a configuration file, if credential helpers are enabled (see WithCredentialHelpers)
+func (o *options) helperCommandOf(envVal string) (string, bool) {
+	if !o.credentialHelpers {
+		return "", false
+	}
+	matches := helperCommandRegexp.FindStringSubmatch(strings.TrimSpace(envVal))
+	if matches == nil {
+		return "", false
+	}
+	return strings.TrimSpace(matches[1]), true
+}
+
+// getHelperCommands returns the credential helper commands of the items of 'config', keyed
+// by environment name, along with their sources; those named by entries of 'configFile' (or
+// that of the selected profile) take precedence over those given by `helper` tags
+func getHelperCommands[T any](config T, configFile string, o *options) (map[string]string, map[string]ValueSource, error) {
+	envItems, getterErr := GetConfigEnvItems(config)
+	if getterErr != nil {
+		return nil, nil, getterErr
+	}
+	commands := make(map[string]string)
+	sources := make(map[string]ValueSource)
+	for _, envItem := range envItems {
+		if envItem.Helper != "" {
+			commands[envItem.Name] = envItem.Helper
+			sources[envItem.Name] = ValueSource{Kind: SourceCredentialHelper}
+		}
+	}
+	if !o.credentialHelpers {
+		return commands, sources, nil
+	}
+	fileEnv, fileSources, readErr := readConfigLayers(configFile, o)
+	if readErr != nil {
+		// a missing or unreadable file names no commands, and is reported when loaded
+		return commands, sources, nil
+	}
+	for envName, envVal := range fileEnv {
+		if command, isHelper := o.helperCommandOf(envVal); isHelper {
+			commands[envName] = command
+			helperSource := fileSources[envName]
+			helperSource.Kind = SourceCredentialHelper
+			sources[envName] = helperSource
+		}
+	}
+	return commands, sources, nil
+}
+
+// runHelper runs the credential helper 'command' (split into arguments at white space,
+// without using a shell), returning its output without its trailing newline
+func runHelper(command string, timeout time.Duration) (string, error) {
+	args := strings.Fields(command)
+	if len(args) == 0 {
+		return "", errors.New("empty credential helper command")
+	}
+	ctx, cancel := context.WithTimeout(context.Background(), timeout)
+	defer cancel()
+	cmd := exec.CommandContext(ctx, args[0], args[1:]...)
+	// e.g., for prompts and diagnostics
+	cmd.Stderr = os.Stderr
+	output, runErr := cmd.Output()
+	if errors.Is(ctx.Err(), context.DeadlineExceeded) {
+		return "", fmt.Errorf("credential helper(%s) timed out after %v", args[0], timeout)
+	}
+	if runErr != nil {
+		return "", fmt.Errorf("credential helper(%s) failed: %w", args[0], runErr)
+	}
+	return trimNewline(string(output)), nil
+}
+
+// helperLookuper is an envconfig.Lookuper resolving environment names to the output of
+// their credential helper commands, each run at most once, and only if looked up
+type helperLookuper struct {
+	commands map[string]string
+	timeout  time.Duration
+	values   map[string]string
+	errs     map[string]error
+}
+
+func newHelperLookuper(commands map[string]string, timeout time.Duration) *helperLookuper {
+	return &helperLookuper{commands: commands, timeout: timeout, values: make(map[string]string), errs: make(map[string]error)}
+}
+
+func (hl *helperLookuper) Lookup(envName string) (string, bool) {
+	command, found := hl.commands[envName]
+	if !found {
+		return "", false
+	}
+	if val, ran := hl.values[envName]; ran {
+		return val, true
+	}
+	if _, failed := hl.errs[envName]; failed {
+		return "", false
+	}
+	val, runErr := runHelper(command, hl.timeout)
+	if runErr != nil {
+		hl.errs[envName] = &FieldError{EnvName: envName, Err: runErr}
+		return "", false
+	}
+	hl.values[envName] = val
+	return val, true
+}
+
+// err returns a FieldError for each credential helper command which failed, if any
+func (hl *helperLookuper) err() error {
+	var helperErrs []error
+	for _, envName := range sortedKeys(hl.errs) {
+		helperErrs = append(helperErrs, hl.errs[envName])
+	}
+	return newMultiError(helperErrs)
+}
@@ -0,0 +1,96 @@
+package configurator
+
+import (
+	"os"
+	"path/filepath"
+	"runtime"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/require"
+)
+
+func TestRunHelper(t *testing.T) {
+
+	if runtime.GOOS == "windows" {
+		t.Skip("helper commands used by the test are POSIX")
+	}
+
+	testCases := []struct {
+		name        string
+		command     string
+		expectedVal string
+		expectedErr string
+	}{
+		{name: "output", command: "echo s3cret", expectedVal: "s3cret"},
+		{name: "failure", command: "false", expectedErr: "credential helper(false) failed: exit status 1"},
+		{name: "timeout", command: "sleep 5", expectedErr: "credential helper(sleep) timed out after 100ms"},
+		{name: "empty", command: " ", expectedErr: "empty credential helper command"},
+	}
+	for _, testCase := range testCases {
+		t.Run(testCase.name, func(t *testing.T) {
+			requirer := require.New(t)
+			val, runErr := runHelper(testCase.command, 100*time.Millisecond)
+			if testCase.expectedErr != "" {
+				requirer.EqualError(runErr, testCase.expectedErr)
+				return
+			}
+			requirer.NoError(runErr)
+			requirer.Equal(testCase.expectedVal, val)
+		})
+	}
+}
+
+func TestApiLoadSaveCredentialHelpers(t *testing.T) {
+
+	if runtime.GOOS == "windows" {
+		t.Skip("helper commands used by the test are POSIX")
+	}
+
+	type testConfig struct {
+		User     string `env:"HELPER_USER"`
+		Password string `env:"HELPER_PASSWORD" secret:"mask"`
+		Token    string `env:"HELPER_TOKEN" secret:"mask" helper:"echo t0ken"`
+	}
+
+	requirer := require.New(t)
+	unsetTestEnv(t, "HELPER_USER", "HELPER_PASSWORD", "HELPER_TOKEN")
+
+	envFileName := filepath.Join(t.TempDir(), "config.env")
+	entries := "HELPER_PASSWORD=$(echo s3cret)\nHELPER_USER=user\n"
+	requirer.NoError(os.WriteFile(envFileName, []byte(entries), 0600))
+
+	// helpers named by the `helper` tag are always run
+	config := testConfig{}
+	requirer.NoError(LoadConfig(envFileName, &config))
+	requirer.Equal(testConfig{User: "user", Password: "$(echo s3cret)", Token: "t0ken"}, config)
+	requirer.NoError(os.Unsetenv("HELPER_PASSWORD"))
+
+	// while those named by entries of the file are run only when allowed
+	config = testConfig{}
+	provenance := make(Provenance)
+	requirer.NoError(LoadConfig(envFileName, &config, WithCredentialHelpers(), WithProvenance(provenance)))
+	requirer.Equal(testConfig{User: "user", Password: "s3cret", Token: "t0ken"}, config)
+	requirer.Equal("credential helper "+envFileName+":1", provenance["HELPER_PASSWORD"].String())
+	requirer.Equal(SourceCredentialHelper, provenance["HELPER_TOKEN"].Kind)
+	_, found := os.LookupEnv("HELPER_PASSWORD")
+	requirer.False(found)
+
+	// their values are never saved
+	config.Password, config.Token = "changed", "changed"
+	requirer.NoError(SaveConfig(envFileName, config, WithCredentialHelpers()))
+	contents, readErr := os.ReadFile(envFileName)
+	requirer.NoError(readErr)
+	requirer.Equal(entries, string(contents))
+	config = testConfig{}
+	requirer.NoError(LoadConfig(envFileName, &config, WithCredentialHelpers()))
+	requirer.Equal("s3cret", config.Password)
+
+	// failures are reported
+	requirer.NoError(os.WriteFile(envFileName, []byte("HELPER_PASSWORD=$(false)\n"), 0600))
+	loadErr := LoadConfig(envFileName, &testConfig{}, WithCredentialHelpers())
+	var fieldErr *FieldError
+	requirer.ErrorAs(loadErr, &fieldErr)
+	requirer.Equal("HELPER_PASSWORD", fieldErr.EnvName)
+	requirer.ErrorContains(loadErr, "credential helper(false) failed")
+}
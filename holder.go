@@ -0,0 +1,116 @@
+package configurator
+
+import (
+	"context"
+	"maps"
+	"sync"
+	"sync/atomic"
+)
+
+// ConfigHolder holds the current configuration of a running application, allowing
+// it to be read, updated and replaced safely by concurrent goroutines.  The structure
+// returned by Get must be treated as read-only; changes are made using Update, which
+// operates on a (shallow) copy.
+type ConfigHolder[T any] struct {
+	configFile string
+	opts       []Option
+	current    atomic.Pointer[T]
+
+	mu          sync.Mutex // serializes changes, and their notification
+	subscribers map[int]func(*T)
+	nextSubId   int
+	// savedContents holds the contents of the watched files (see Watch) as last saved by Update
+	savedContents map[string][]byte
+}
+
+// NewConfigHolder returns a ConfigHolder initially holding 'config', whose updates
+// are saved into 'configFile' using 'opts' (see SaveConfig)
+func NewConfigHolder[T any](configFile string, config *T, opts ...Option) *ConfigHolder[T] {
+	holder := &ConfigHolder[T]{
+		configFile:  configFile,
+		opts:        opts,
+		subscribers: make(map[int]func(*T)),
+	}
+	holder.current.Store(config)
+	return holder
+}
+
+// Get returns the current configuration, without locking
+func (ch *ConfigHolder[T]) Get() *T {
+	return ch.current.Load()
+}
+
+// Update applies 'update' to a copy of the current configuration then validates
+// (see Validator) and saves it.  If successful, the copy becomes the current
+// configuration and subscribers are notified; otherwise the current configuration
+// remains unchanged and the error is returned.
+func (ch *ConfigHolder[T]) Update(update func(*T) error) error {
+	ch.mu.Lock()
+	defer ch.mu.Unlock()
+
+	updated := *ch.current.Load()
+	if updateErr := update(&updated); updateErr != nil {
+		return updateErr
+	}
+	if validateErr := validateConfig(&updated); validateErr != nil {
+		return validateErr
+	}
+	if saveErr := SaveConfig(ch.configFile, updated, ch.opts...); saveErr != nil {
+		return saveErr
+	}
+	options := newOptions(ch.opts)
+	ch.savedContents, _ = readWatchedFiles(watchedFiles(ch.configFile, options))
+	ch.replace(&updated)
+	return nil
+}
+
+// Replace makes 'config' the current configuration, without validating or saving it,
+// and notifies subscribers
+func (ch *ConfigHolder[T]) Replace(config *T) {
+	ch.mu.Lock()
+	defer ch.mu.Unlock()
+	ch.replace(config)
+}
+
+// Watch keeps the current configuration in sync with changes made to the
+// configuration file by others, until 'ctx' is done (see WatchConfig); changes
+// saved by Update, already notified, aren't loaded again
+func (ch *ConfigHolder[T]) Watch(ctx context.Context, onError func(error)) {
+	watchConfig(ctx, ch.configFile, ch.Replace, onError, ch.isOwnWrite, ch.opts...)
+}
+
+// isOwnWrite returns true if 'contents' of the watched files are those last saved by Update,
+// which are then forgotten, so that only the first change to them is taken as its own
+func (ch *ConfigHolder[T]) isOwnWrite(contents map[string][]byte) bool {
+	ch.mu.Lock()
+	defer ch.mu.Unlock()
+	savedContents := ch.savedContents
+	ch.savedContents = nil
+	return savedContents != nil && maps.EqualFunc(contents, savedContents, sameWatchedContents)
+}
+
+// Subscribe registers 'onChange' to be called with each new current configuration,
+// returning a function which cancels the registration.  Since changes are notified
+// while being serialized, 'onChange' must not itself call Update, Replace or
+// the returned function.
+func (ch *ConfigHolder[T]) Subscribe(onChange func(*T)) (unsubscribe func()) {
+	ch.mu.Lock()
+	defer ch.mu.Unlock()
+	subId := ch.nextSubId
+	ch.nextSubId++
+	ch.subscribers[subId] = onChange
+	return func() {
+		ch.mu.Lock()
+		defer ch.mu.Unlock()
+		delete(ch.subscribers, subId)
+	}
+}
+
+// replace stores 'config' as the current configuration and notifies subscribers;
+// 'ch.mu' must be held by the caller
+func (ch *ConfigHolder[T]) replace(config *T) {
+	ch.current.Store(config)
+	for _, onChange := range ch.subscribers {
+		onChange(config)
+	}
+}
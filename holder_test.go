@@ -0,0 +1,143 @@
+package configurator
+
+import (
+	"context"
+	"errors"
+	"os"
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/require"
+)
+
+type heldTestConfig struct {
+	S1 string `env:"HELD_S1"`
+	I2 int    `env:"HELD_I2"`
+}
+
+func (htc heldTestConfig) Validate() error {
+	if htc.I2 < 0 {
+		return errors.New("HELD_I2 must not be negative")
+	}
+	return nil
+}
+
+func TestConfigHolder(t *testing.T) {
+
+	requirer := require.New(t)
+	t.Setenv("HELD_S1", "")
+	t.Setenv("HELD_I2", "")
+
+	envFileName, ctefErr := createTempEnvFileFromMap(t, map[string]any{})
+	requirer.NoError(ctefErr)
+
+	initial := &heldTestConfig{S1: "initial"}
+	holder := NewConfigHolder(envFileName, initial)
+	requirer.Same(initial, holder.Get())
+
+	// subscribers are notified in no particular order
+	type notification struct {
+		subscriber string
+		i2         int
+	}
+	var notified []notification
+	unsubscribe := holder.Subscribe(func(c *heldTestConfig) { notified = append(notified, notification{"first", c.I2}) })
+	unsubscribeSecond := holder.Subscribe(func(c *heldTestConfig) { notified = append(notified, notification{"second", c.I2}) })
+
+	// concurrent readers see either the previous or the updated configuration
+	var wg sync.WaitGroup
+	readI2s := make(chan int, 4*100)
+	for reader := 0; reader < 4; reader++ {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			for read := 0; read < 100; read++ {
+				readI2s <- holder.Get().I2
+			}
+		}()
+	}
+	for update := 1; update <= 10; update++ {
+		requirer.NoError(holder.Update(func(c *heldTestConfig) error {
+			c.I2++
+			return nil
+		}))
+	}
+	wg.Wait()
+	close(readI2s)
+	for readI2 := range readI2s {
+		requirer.GreaterOrEqual(readI2, 0)
+		requirer.LessOrEqual(readI2, 10)
+	}
+
+	requirer.Equal(heldTestConfig{S1: "initial", I2: 10}, *holder.Get())
+	requirer.Equal(heldTestConfig{S1: "initial"}, *initial, "held configuration must not be modified")
+	var expectedNotified []notification
+	for i2 := 1; i2 <= 10; i2++ {
+		expectedNotified = append(expectedNotified, notification{"first", i2}, notification{"second", i2})
+	}
+	requirer.ElementsMatch(expectedNotified, notified)
+	contents, readErr := os.ReadFile(envFileName)
+	requirer.NoError(readErr)
+	requirer.Equal("HELD_I2=10\nHELD_S1=initial\n", string(contents))
+
+	// failed updates leave the configuration unchanged
+	requirer.ErrorContains(holder.Update(func(c *heldTestConfig) error {
+		c.I2 = -1
+		return nil
+	}), "must not be negative")
+	requirer.EqualError(holder.Update(func(c *heldTestConfig) error {
+		c.S1 = "changed"
+		return errors.New("rejected")
+	}), "rejected")
+	requirer.Equal(heldTestConfig{S1: "initial", I2: 10}, *holder.Get())
+
+	unsubscribe()
+	holder.Replace(&heldTestConfig{S1: "replaced"})
+	requirer.Equal("replaced", holder.Get().S1)
+	requirer.ElementsMatch(append(expectedNotified, notification{"second", 0}), notified)
+	unsubscribeSecond()
+}
+
+func TestConfigHolderWatch(t *testing.T) {
+
+	requirer := require.New(t)
+	t.Setenv("HELD_S1", "")
+	t.Setenv("HELD_I2", "")
+
+	envFileName, ctefErr := createTempEnvFileFromMap(t, map[string]any{"HELD_S1": "initial"})
+	requirer.NoError(ctefErr)
+	holder := NewConfigHolder(envFileName, &heldTestConfig{S1: "initial"}, WithWatchInterval(5*time.Millisecond))
+	notified := make(chan heldTestConfig, 10)
+	defer holder.Subscribe(func(c *heldTestConfig) { notified <- *c })()
+	ctx, cancel := context.WithCancel(context.Background())
+	defer cancel()
+	holder.Watch(ctx, func(err error) { requirer.Fail("unexpected error", err) })
+
+	awaitNotified := func() heldTestConfig {
+		select {
+		case c := <-notified:
+			return c
+		case <-time.After(5 * time.Second):
+			requirer.FailNow("timed out awaiting notification")
+		}
+		return heldTestConfig{}
+	}
+
+	// changes saved by Update are notified once, rather than again when the watcher sees them
+	requirer.NoError(holder.Update(func(c *heldTestConfig) error {
+		c.I2 = 1
+		return nil
+	}))
+	requirer.Equal(heldTestConfig{S1: "initial", I2: 1}, awaitNotified())
+	select {
+	case c := <-notified:
+		requirer.FailNow("unexpected notification", c)
+	case <-time.After(100 * time.Millisecond):
+	}
+
+	// while changes made by others are (written atomically, so as not to be seen half written)
+	requirer.NoError(os.WriteFile(envFileName+".tmp", []byte("HELD_I2=2\nHELD_S1=other\n"), 0600))
+	requirer.NoError(os.Rename(envFileName+".tmp", envFileName))
+	requirer.Equal(heldTestConfig{S1: "other", I2: 2}, awaitNotified())
+}
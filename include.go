@@ -0,0 +1,184 @@
+package configurator
+
+import (
+	"bufio"
+	"bytes"
+	"errors"
+	"fmt"
+	"io"
+	"io/fs"
+	"os"
+	"path/filepath"
+	"regexp"
+	"slices"
+	"strings"
+)
+
+// includeDirectivePattern matches a line of a dotenv configuration file including the entries of
+// another file (e.g., "#include ./common.env"), resolved relative to the including file; being a
+// comment, it's ignored by other dotenv tools
+var includeDirectivePattern = regexp.MustCompile(`^\s*#include\s+(\S.*?)\s*$`)
+
+// includeDirective is an include directive found on a line of a dotenv configuration file
+type includeDirective struct {
+	text string // the text of the line holding the directive
+	file string // the name of the included file, as written
+	line int
+}
+
+// findIncludeDirectives returns the include directives found within 'contents' of a configuration
+// file written in 'format'; only dotenv files may hold them
+func findIncludeDirectives(contents []byte, format Format) []includeDirective {
+	if format != DotenvFormat {
+		return nil
+	}
+	var directives []includeDirective
+	scanner := bufio.NewScanner(bytes.NewReader(contents))
+	for lineNumber := 1; scanner.Scan(); lineNumber++ {
+		if matches := includeDirectivePattern.FindStringSubmatch(scanner.Text()); matches != nil {
+			includedFile := strings.Trim(matches[1], `"'`)
+			directives = append(directives, includeDirective{text: strings.TrimSpace(scanner.Text()), file: includedFile, line: lineNumber})
+		}
+	}
+	return directives
+}
+
+// resolveInclude returns the name of the file included by 'directive' within 'configFile'
+func resolveInclude(configFile string, directive includeDirective) string {
+	if filepath.IsAbs(directive.file) {
+		return directive.file
+	}
+	return filepath.Join(filepath.Dir(configFile), directive.file)
+}
+
+// includedFiles returns the names of the files included by 'configFile', directly or indirectly,
+// whether or not they exist
+func includedFiles(configFile string, o *options) []string {
+	var files []string
+	visited := map[string]bool{filepath.Clean(configFile): true}
+	var visit func(fileName string)
+	visit = func(fileName string) {
+		contents, readErr := os.ReadFile(fileName)
+		if readErr != nil {
+			return
+		}
+		for _, directive := range findIncludeDirectives(contents, o.formatFor(fileName)) {
+			includedFile := resolveInclude(fileName, directive)
+			if visited[filepath.Clean(includedFile)] {
+				continue
+			}
+			visited[filepath.Clean(includedFile)] = true
+			files = append(files, includedFile)
+			visit(includedFile)
+		}
+	}
+	visit(configFile)
+	return files
+}
+
+// readIncludingEntries returns the entries of 'configFile', including those of the files it
+// includes (see includeDirectivePattern), together with their sources; its own entries take
+// precedence over included ones, which take precedence over those included before them.
+// 'including' names the files (directly or indirectly) including 'configFile'.
+func readIncludingEntries(configFile string, o *options, including []string) (map[string]string, map[string]ValueSource, error) {
+	contents, readErr := os.ReadFile(configFile)
+	if readErr != nil {
+		return nil, nil, &FileError{File: configFile, Err: readErr}
+	}
+	format := o.formatFor(configFile)
+	ownEnv, parseErr := parseConfigFile(configFile, contents, format)
+	if parseErr != nil {
+		return nil, nil, parseErr
+	}
+	ownEnv = o.templatesOf(contents, format, ownEnv)
+
+	fileEnv := make(map[string]string, len(ownEnv))
+	fileSources := make(map[string]ValueSource, len(ownEnv))
+	including = append(slices.Clip(including), filepath.Clean(configFile))
+	for _, directive := range findIncludeDirectives(contents, format) {
+		includedFile := resolveInclude(configFile, directive)
+		if slices.Contains(including, filepath.Clean(includedFile)) {
+			cycle := strings.Join(append(slices.Clone(including), filepath.Clean(includedFile)), " -> ")
+			return nil, nil, &FileError{File: configFile, Line: directive.line, Err: fmt.Errorf("%w: %s", ErrIncludeCycle, cycle)}
+		}
+		includedEnv, includedSources, includeErr := readIncludingEntries(includedFile, o, including)
+		if errors.Is(includeErr, fs.ErrNotExist) {
+			// unlike a missing configuration file, a missing included one isn't benign
+			return nil, nil, &FileError{File: configFile, Line: directive.line, Err: fmt.Errorf("included file(%s) not found", directive.file)}
+		}
+		if includeErr != nil {
+			return nil, nil, includeErr
+		}
+		for envName, envVal := range includedEnv {
+			fileEnv[envName] = envVal
+			fileSources[envName] = includedSources[envName]
+		}
+	}
+
+	entryLines := findEntryLines(contents, format)
+	for envName, envVal := range ownEnv {
+		fileEnv[envName] = envVal
+		fileSources[envName] = ValueSource{Kind: SourceFile, File: configFile, Line: entryLines[envName]}
+	}
+	return fileEnv, fileSources, nil
+}
+
+// readIncludeDirectives returns the text of the include directives within 'configFile', if any
+func readIncludeDirectives(configFile string, format Format) []string {
+	contents, readErr := os.ReadFile(configFile)
+	if readErr != nil {
+		return nil
+	}
+	var directiveTexts []string
+	for _, directive := range findIncludeDirectives(contents, format) {
+		directiveTexts = append(directiveTexts, directive.text)
+	}
+	return directiveTexts
+}
+
+// writeIncludeDirectives writes the include directives 'directiveTexts' to 'w'
+func writeIncludeDirectives(w io.Writer, directiveTexts []string) error {
+	for _, directiveText := range directiveTexts {
+		if _, printErr := fmt.Fprintln(w, directiveText); printErr != nil {
+			return printErr
+		}
+	}
+	return nil
+}
+
+// splitIncludedEntries returns the entries of 'configMap' to be saved into 'configFile', along
+// with the entries of the files it includes which are to be saved into them, keyed by file name.
+// Entries found in an included file are saved into it if changed, otherwise left there.
+func splitIncludedEntries(configFile string, configMap map[string]any, o *options) (map[string]any, map[string]map[string]any) {
+	_, fileSources, readErr := readIncludingEntries(configFile, o, nil)
+	if readErr != nil {
+		return configMap, nil
+	}
+	ownMap := make(map[string]any, len(configMap))
+	includedMaps := make(map[string]map[string]any)
+	for envName, envVal := range configMap {
+		owner := fileSources[envName].File
+		if owner == "" || owner == configFile {
+			ownMap[envName] = envVal
+			continue
+		}
+		ownerEnv, ownerErr := o.readTemplates(owner)
+		if ownerErr != nil {
+			ownMap[envName] = envVal
+			continue
+		}
+		if envVal != nil && fmt.Sprintf("%v", envVal) == ownerEnv[envName] {
+			continue
+		}
+		includedMap, found := includedMaps[owner]
+		if !found {
+			includedMap = make(map[string]any, len(ownerEnv))
+			for ownerName, ownerVal := range ownerEnv {
+				includedMap[ownerName] = ownerVal
+			}
+			includedMaps[owner] = includedMap
+		}
+		includedMap[envName] = envVal
+	}
+	return ownMap, includedMaps
+}
@@ -0,0 +1,152 @@
+package configurator
+
+import (
+	"os"
+	"path/filepath"
+	"testing"
+
+	"github.com/stretchr/testify/require"
+)
+
+func TestFindIncludeDirectives(t *testing.T) {
+
+	testCases := []struct {
+		name               string
+		contents           string
+		format             Format
+		expectedDirectives []includeDirective
+	}{
+		{
+			name:     "directives",
+			contents: "#include ./common.env\nA=1\n  #include \"shared/secrets.env\"  \n",
+			format:   DotenvFormat,
+			expectedDirectives: []includeDirective{
+				{text: "#include ./common.env", file: "./common.env", line: 1},
+				{text: `#include "shared/secrets.env"`, file: "shared/secrets.env", line: 3},
+			},
+		},
+		{name: "comments", contents: "# include ./common.env\n#included\nA=1 #include x\n", format: DotenvFormat},
+		{name: "not dotenv", contents: "#include ./common.yaml\nA: 1\n", format: YAMLFormat},
+	}
+	for _, testCase := range testCases {
+		t.Run(testCase.name, func(t *testing.T) {
+			require.Equal(t, testCase.expectedDirectives, findIncludeDirectives([]byte(testCase.contents), testCase.format))
+		})
+	}
+}
+
+func TestApiLoadSaveIncludes(t *testing.T) {
+
+	type testConfig struct {
+		Host  string `env:"INCLUDE_HOST"`
+		Port  int    `env:"INCLUDE_PORT"`
+		Level string `env:"INCLUDE_LEVEL"`
+	}
+
+	requirer := require.New(t)
+	unsetTestEnv(t, "INCLUDE_HOST", "INCLUDE_PORT", "INCLUDE_LEVEL")
+
+	tempDir := t.TempDir()
+	sharedDir := filepath.Join(tempDir, "shared")
+	requirer.NoError(os.Mkdir(sharedDir, 0700))
+	commonFileName := filepath.Join(sharedDir, "common.env")
+	envFileName := filepath.Join(tempDir, "host.env")
+	requirer.NoError(os.WriteFile(commonFileName, []byte("INCLUDE_LEVEL=info\nINCLUDE_PORT=80\n"), 0600))
+	hostEntries := "#include shared/common.env\nINCLUDE_HOST=host1\nINCLUDE_PORT=8080\n"
+	requirer.NoError(os.WriteFile(envFileName, []byte(hostEntries), 0600))
+
+	// the including file's entries take precedence over those it includes
+	config := testConfig{}
+	provenance := make(Provenance)
+	requirer.NoError(LoadConfig(envFileName, &config, WithProvenance(provenance)))
+	requirer.Equal(testConfig{Host: "host1", Port: 8080, Level: "info"}, config)
+	requirer.Equal(ValueSource{Kind: SourceFile, File: commonFileName, Line: 1}, provenance["INCLUDE_LEVEL"])
+	requirer.Equal(ValueSource{Kind: SourceFile, File: envFileName, Line: 3}, provenance["INCLUDE_PORT"])
+
+	// unchanged, nothing is moved between the files
+	requirer.NoError(SaveConfig(envFileName, config))
+	contents, readErr := os.ReadFile(envFileName)
+	requirer.NoError(readErr)
+	requirer.Equal(hostEntries, string(contents))
+
+	// changes are saved into the file owning the entry
+	config.Level = "debug"
+	config.Host = "host2"
+	requirer.NoError(SaveConfig(envFileName, config))
+	contents, readErr = os.ReadFile(commonFileName)
+	requirer.NoError(readErr)
+	requirer.Equal("INCLUDE_LEVEL=debug\nINCLUDE_PORT=80\n", string(contents))
+	contents, readErr = os.ReadFile(envFileName)
+	requirer.NoError(readErr)
+	requirer.Equal("#include shared/common.env\nINCLUDE_HOST=host2\nINCLUDE_PORT=8080\n", string(contents))
+	config = testConfig{}
+	requirer.NoError(LoadConfig(envFileName, &config))
+	requirer.Equal(testConfig{Host: "host2", Port: 8080, Level: "debug"}, config)
+
+	// the version of the configuration is recorded only in the including file
+	config.Level = "warn"
+	requirer.NoError(SaveConfig(envFileName, config, WithMigrations(Migration{Version: 1})))
+	contents, readErr = os.ReadFile(commonFileName)
+	requirer.NoError(readErr)
+	requirer.Equal("INCLUDE_LEVEL=warn\nINCLUDE_PORT=80\n", string(contents))
+	contents, readErr = os.ReadFile(envFileName)
+	requirer.NoError(readErr)
+	requirer.Equal("#include shared/common.env\nCONFIG_VERSION=1\nINCLUDE_HOST=host2\nINCLUDE_PORT=8080\n", string(contents))
+
+	// a missing included file is an error, unlike a missing configuration file
+	requirer.NoError(os.WriteFile(envFileName, []byte("INCLUDE_HOST=host1\n#include missing.env\n"), 0600))
+	loadErr := LoadConfig(envFileName, &testConfig{})
+	var fileErr *FileError
+	requirer.ErrorAs(loadErr, &fileErr)
+	requirer.Equal(envFileName, fileErr.File)
+	requirer.Equal(2, fileErr.Line)
+	requirer.ErrorContains(loadErr, "included file(missing.env) not found")
+
+	// as is a cycle
+	requirer.NoError(os.WriteFile(commonFileName, []byte("#include ../host.env\n"), 0600))
+	requirer.NoError(os.WriteFile(envFileName, []byte("#include shared/common.env\n"), 0600))
+	loadErr = LoadConfig(envFileName, &testConfig{})
+	requirer.ErrorIs(loadErr, ErrIncludeCycle)
+	requirer.ErrorContains(loadErr, envFileName+" -> "+commonFileName+" -> "+envFileName)
+}
+
+func TestApiSaveIncludedLiteral(t *testing.T) {
+
+	type testConfig struct {
+		Host     string `env:"INCLUDE_LIT_HOST"`
+		Password string `env:"INCLUDE_LIT_PASSWORD"`
+		Level    string `env:"INCLUDE_LIT_LEVEL"`
+	}
+
+	requirer := require.New(t)
+	unsetTestEnv(t, "INCLUDE_LIT_HOST", "INCLUDE_LIT_PASSWORD", "INCLUDE_LIT_LEVEL")
+	t.Setenv("SWORD", "sword")
+
+	tempDir := t.TempDir()
+	commonFileName := filepath.Join(tempDir, "common.env")
+	envFileName := filepath.Join(tempDir, "host.env")
+	commonEntries := "INCLUDE_LIT_LEVEL=info\nINCLUDE_LIT_PASSWORD='pa$SWORD'\n"
+	requirer.NoError(os.WriteFile(commonFileName, []byte(commonEntries), 0600))
+	requirer.NoError(os.WriteFile(envFileName, []byte("#include common.env\nINCLUDE_LIT_HOST=host1\n"), 0600))
+
+	config := testConfig{}
+	requirer.NoError(LoadConfig(envFileName, &config))
+	requirer.Equal(testConfig{Host: "host1", Password: "pa$SWORD", Level: "info"}, config)
+
+	// unchanged literal values are left in the included file
+	config.Host = "host2"
+	requirer.NoError(SaveConfig(envFileName, config))
+	contents, readErr := os.ReadFile(envFileName)
+	requirer.NoError(readErr)
+	requirer.Equal("#include common.env\nINCLUDE_LIT_HOST=host2\n", string(contents))
+	contents, readErr = os.ReadFile(commonFileName)
+	requirer.NoError(readErr)
+	requirer.Equal(commonEntries, string(contents))
+
+	// nor are they expanded when saving changes into it
+	config.Level = "debug"
+	requirer.NoError(SaveConfig(envFileName, config))
+	loadedConfig := testConfig{}
+	requirer.NoError(LoadConfig(envFileName, &loadedConfig))
+	requirer.Equal(config, loadedConfig)
+}
@@ -0,0 +1,208 @@
+package configurator
+
+import (
+	"bufio"
+	"bytes"
+	"fmt"
+	"os"
+	"regexp"
+	"slices"
+	"strings"
+)
+
+// referenceRegexp matches a reference to a variable within a configuration value, i.e.,
+// "${NAME}" or "$NAME" (the latter only for upper case names), optionally escaped as "\$"
+var referenceRegexp = regexp.MustCompile(`\\?\$(?:\{[A-Za-z_][A-Za-z0-9_]*\}|[A-Z_][A-Z0-9_]*)`)
+
+// interpolate returns the entries of a configuration file with the references to variables
+// within their values (see referenceRegexp) expanded, unless given WithoutInterpolation.
+// References to other entries of the
+// file are expanded to their expanded values, unless shadowed by the environment (see
+// WithFileOverridingEnv), while others are expanded to their values in the environment,
+// or "" if not set.  A FieldError wrapping ErrInterpolationCycle is returned if an entry
+// refers to itself, directly or indirectly.
+func (o *options) interpolate(fileEnv map[string]string) (map[string]string, error) {
+	expandedEnv := make(map[string]string, len(fileEnv))
+	if o.literalValues {
+		for envName, envVal := range fileEnv {
+			expandedEnv[envName] = envVal
+		}
+		return expandedEnv, nil
+	}
+	var expanding []string
+	var expand func(envName string) (string, error)
+	expand = func(envName string) (string, error) {
+		if expandedVal, expanded := expandedEnv[envName]; expanded {
+			return expandedVal, nil
+		}
+		if cycleStart := slices.Index(expanding, envName); cycleStart >= 0 {
+			cycle := strings.Join(append(expanding[cycleStart:], envName), " -> ")
+			return "", &FieldError{EnvName: envName, Err: fmt.Errorf("%w: %s", ErrInterpolationCycle, cycle)}
+		}
+		expanding = append(expanding, envName)
+		defer func() { expanding = expanding[:len(expanding)-1] }()
+
+		var expandErr error
+		expandedVal := referenceRegexp.ReplaceAllStringFunc(fileEnv[envName], func(reference string) string {
+			if escaped, isEscaped := strings.CutPrefix(reference, `\`); isEscaped || expandErr != nil {
+				return escaped
+			}
+			referenceName := strings.Trim(reference, "${}")
+			if _, inFile := fileEnv[referenceName]; inFile && !o.isShadowed(referenceName) {
+				referenceVal, referenceErr := expand(referenceName)
+				expandErr = referenceErr
+				return referenceVal
+			}
+			return os.Getenv(referenceName)
+		})
+		if expandErr != nil {
+			return "", expandErr
+		}
+		expandedEnv[envName] = expandedVal
+		return expandedVal, nil
+	}
+
+	for _, envName := range sortedKeys(fileEnv) {
+		if _, expandErr := expand(envName); expandErr != nil {
+			return nil, expandErr
+		}
+	}
+	return expandedEnv, nil
+}
+
+// expandConfigMap returns the values of the entries of 'configMap' to be loaded into the
+// environment, with references to variables expanded (see interpolate); entries having
+// nil values are omitted
+func (o *options) expandConfigMap(configMap map[string]any) (map[string]string, error) {
+	fileEnv := make(map[string]string, len(configMap))
+	for envName, envVal := range configMap {
+		if envVal != nil {
+			fileEnv[envName] = fmt.Sprintf("%v", envVal)
+		}
+	}
+	return o.interpolate(fileEnv)
+}
+
+// isShadowed returns whether the entry of a configuration file named 'envName' is shadowed
+// by a variable set into the environment other than by configurator
+func (o *options) isShadowed(envName string) bool {
+	_, isExternal := lookupExternalEnv(envName)
+	return isExternal && !o.fileOverridesEnv
+}
+
+// escapeReferences returns 'envVal' with the references to variables within it (see
+// referenceRegexp) escaped, so that it's loaded as is
+func escapeReferences(envVal string) string {
+	return referenceRegexp.ReplaceAllStringFunc(envVal, func(reference string) string {
+		return `\` + reference
+	})
+}
+
+// templateFor returns the value of the item named 'envName' to be saved into a configuration
+// file holding the entries 'fileEnv', which expand to 'expandedEnv': its template, if it still
+// expands to 'envVal', otherwise 'envVal' with any references escaped; 'envVal' if given
+// WithoutInterpolation
+func (o *options) templateFor(envName string, envVal any, fileEnv, expandedEnv map[string]string) any {
+	if o.literalValues {
+		return envVal
+	}
+	stringVal := fmt.Sprintf("%v", envVal)
+	if expandedVal, expanded := expandedEnv[envName]; expanded && expandedVal == stringVal && fileEnv[envName] != stringVal {
+		return fileEnv[envName]
+	}
+	if escapedVal := escapeReferences(stringVal); escapedVal != stringVal {
+		return escapedVal
+	}
+	return envVal
+}
+
+// templatesOf returns 'fileEnv', the entries found within 'contents' of a configuration file
+// written in 'format', as the templates to be expanded by interpolate: since single quoted
+// dotenv values are literal, the references within them are escaped
+func (o *options) templatesOf(contents []byte, format Format, fileEnv map[string]string) map[string]string {
+	if o.literalValues || format != DotenvFormat {
+		return fileEnv
+	}
+	for envName, isSingleQuoted := range findSingleQuotedEntries(contents) {
+		if envVal, found := fileEnv[envName]; found && isSingleQuoted {
+			fileEnv[envName] = escapeReferences(envVal)
+		}
+	}
+	return fileEnv
+}
+
+// readTemplates returns the entries of 'configFile' as the templates to be expanded by
+// interpolate (see templatesOf)
+func (o *options) readTemplates(configFile string) (map[string]string, error) {
+	contents, readErr := os.ReadFile(configFile)
+	if readErr != nil {
+		return nil, &FileError{File: configFile, Err: readErr}
+	}
+	format := o.formatFor(configFile)
+	fileEnv, parseErr := parseConfigFile(configFile, contents, format)
+	if parseErr != nil {
+		return nil, parseErr
+	}
+	return o.templatesOf(contents, format, fileEnv), nil
+}
+
+// findSingleQuotedEntries returns whether the values of the entries found within dotenv 'contents'
+// are single quoted (i.e., literal); when an entry appears more than once, the last wins
+func findSingleQuotedEntries(contents []byte) map[string]bool {
+	singleQuoted := make(map[string]bool)
+	scanEntries(contents, DotenvFormat, func(envName string, _ int, val string) {
+		singleQuoted[envName] = strings.HasPrefix(strings.TrimLeft(val, " \t"), "'")
+	})
+	return singleQuoted
+}
+
+// entryLinePattern matches the start of a line holding a configuration entry in any
+// supported format (e.g., "NAME=", "export NAME=", "NAME:", "NAME =" or "\"NAME\":")
+var entryLinePattern = regexp.MustCompile(`^\s*(?:export\s+)?["']?([A-Za-z_][A-Za-z0-9_.-]*)["']?\s*[=:]`)
+
+// scanEntries calls 'found' with the name, line number and (start of the) value of each of the
+// configuration entries found within 'contents' (written in 'format'), in order
+func scanEntries(contents []byte, format Format, found func(envName string, lineNumber int, val string)) {
+	var openQuote byte
+	scanner := bufio.NewScanner(bytes.NewReader(contents))
+	for lineNumber := 1; scanner.Scan(); lineNumber++ {
+		line := scanner.Text()
+		if openQuote != 0 {
+			if closesQuote(line, openQuote) {
+				openQuote = 0
+			}
+			continue
+		}
+		match := entryLinePattern.FindStringSubmatchIndex(line)
+		if match == nil {
+			continue
+		}
+		found(line[match[2]:match[3]], lineNumber, line[match[1]:])
+		if format == DotenvFormat {
+			openQuote = openedQuote(line[match[1]:])
+		}
+	}
+}
+
+// openedQuote returns the quote starting the dotenv value 'val' if it continues onto the
+// following lines (i.e., isn't closed within 'val'), otherwise 0
+func openedQuote(val string) byte {
+	val = strings.TrimLeft(val, " \t")
+	if val == "" || (val[0] != '"' && val[0] != '\'') || closesQuote(val[1:], val[0]) {
+		return 0
+	}
+	return val[0]
+}
+
+// closesQuote returns true if 'text' holds 'quote', other than escaped by a backslash
+func closesQuote(text string, quote byte) bool {
+	for i := 0; i < len(text); i++ {
+		switch text[i] {
+		case '\\':
+			i++
+		case quote:
+			return true
+		}
+	}
+	return false
+}
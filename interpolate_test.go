@@ -0,0 +1,161 @@
+package configurator
+
+import (
+	"os"
+	"path/filepath"
+	"testing"
+
+	"github.com/stretchr/testify/require"
+)
+
+func TestInterpolate(t *testing.T) {
+
+	t.Setenv("INTERP_HOME", "/home/user")
+	t.Setenv("INTERP_SHADOWING", "from env")
+
+	testCases := []struct {
+		name        string
+		fileEnv     map[string]string
+		expectedEnv map[string]string
+		expectedErr string
+	}{
+		{
+			name:        "environment",
+			fileEnv:     map[string]string{"DIR": "${INTERP_HOME}/.local/share/app", "UNSET": "[${INTERP_UNSET}]"},
+			expectedEnv: map[string]string{"DIR": "/home/user/.local/share/app", "UNSET": "[]"},
+		},
+		{
+			name:        "entries",
+			fileEnv:     map[string]string{"LOG": "$DIR/app.log", "DIR": "${HOME_DIR}/app", "HOME_DIR": "/srv"},
+			expectedEnv: map[string]string{"LOG": "/srv/app/app.log", "DIR": "/srv/app", "HOME_DIR": "/srv"},
+		},
+		{
+			name:        "shadowed",
+			fileEnv:     map[string]string{"INTERP_SHADOWING": "from file", "REF": "${INTERP_SHADOWING}"},
+			expectedEnv: map[string]string{"INTERP_SHADOWING": "from file", "REF": "from env"},
+		},
+		{
+			name:        "literal",
+			fileEnv:     map[string]string{"ESCAPED": `\${INTERP_HOME} \$INTERP_HOME`, "PLAIN": "pa$$word $lower $1"},
+			expectedEnv: map[string]string{"ESCAPED": "${INTERP_HOME} $INTERP_HOME", "PLAIN": "pa$$word $lower $1"},
+		},
+		{
+			name:        "cycle",
+			fileEnv:     map[string]string{"A": "${B}", "B": "x${C}", "C": "$A", "D": "d"},
+			expectedErr: "invalid(A): interpolation cycle: A -> B -> C -> A",
+		},
+		{
+			name:        "self",
+			fileEnv:     map[string]string{"A": "${A}"},
+			expectedErr: "invalid(A): interpolation cycle: A -> A",
+		},
+	}
+	for _, testCase := range testCases {
+		t.Run(testCase.name, func(t *testing.T) {
+			requirer := require.New(t)
+			expandedEnv, interpolateErr := newOptions(nil).interpolate(testCase.fileEnv)
+			if testCase.expectedErr != "" {
+				requirer.EqualError(interpolateErr, testCase.expectedErr)
+				requirer.ErrorIs(interpolateErr, ErrInterpolationCycle)
+				return
+			}
+			requirer.NoError(interpolateErr)
+			requirer.Equal(testCase.expectedEnv, expandedEnv)
+		})
+	}
+}
+
+func TestApiLoadSaveInterpolated(t *testing.T) {
+
+	type testConfig struct {
+		DataDir  string `env:"INTERP_DATA_DIR"`
+		LogFile  string `env:"INTERP_LOG_FILE"`
+		Password string `env:"INTERP_PASSWORD"`
+	}
+
+	requirer := require.New(t)
+	unsetTestEnv(t, "INTERP_DATA_DIR", "INTERP_LOG_FILE", "INTERP_PASSWORD")
+	t.Setenv("INTERP_HOME", "/home/user")
+
+	envFileName := filepath.Join(t.TempDir(), "config.env")
+	templates := "INTERP_DATA_DIR=${INTERP_HOME}/.local/share/app\nINTERP_LOG_FILE=${INTERP_DATA_DIR}/app.log\n"
+	requirer.NoError(os.WriteFile(envFileName, []byte(templates), 0600))
+
+	// references are expanded when loaded, both into the config structure and the environment
+	config := testConfig{}
+	requirer.NoError(LoadConfig(envFileName, &config))
+	requirer.Equal(testConfig{DataDir: "/home/user/.local/share/app", LogFile: "/home/user/.local/share/app/app.log"}, config)
+	requirer.Equal(config.LogFile, os.Getenv("INTERP_LOG_FILE"))
+
+	// but not when read
+	configMap, readErr := ReadConfigMap(envFileName)
+	requirer.NoError(readErr)
+	requirer.Equal("${INTERP_DATA_DIR}/app.log", configMap["INTERP_LOG_FILE"])
+
+	// templates of unchanged values are saved, while values containing references are escaped
+	config.Password = "pa$$${WORD}"
+	changes, diffErr := DiffConfigFile(envFileName, config)
+	requirer.NoError(diffErr)
+	requirer.Len(changes, 1)
+	requirer.NoError(SaveConfig(envFileName, config))
+	contents, readErr := os.ReadFile(envFileName)
+	requirer.NoError(readErr)
+	requirer.Equal(templates+"INTERP_PASSWORD=pa$$\\${WORD}\n", string(contents))
+	requirer.Equal("pa$$${WORD}", os.Getenv("INTERP_PASSWORD"))
+
+	loadedConfig := testConfig{}
+	requirer.NoError(LoadConfig(envFileName, &loadedConfig))
+	requirer.Equal(config, loadedConfig)
+
+	// changed values replace their templates
+	config.LogFile = "/var/log/app.log"
+	requirer.NoError(SaveConfig(envFileName, config))
+	contents, readErr = os.ReadFile(envFileName)
+	requirer.NoError(readErr)
+	requirer.Contains(string(contents), "INTERP_LOG_FILE=/var/log/app.log\n")
+	requirer.Contains(string(contents), "INTERP_DATA_DIR=${INTERP_HOME}/.local/share/app\n")
+
+	// cycles are reported
+	requirer.NoError(os.WriteFile(envFileName, []byte("INTERP_DATA_DIR=${INTERP_LOG_FILE}\nINTERP_LOG_FILE=${INTERP_DATA_DIR}\n"), 0600))
+	requirer.ErrorIs(LoadConfig(envFileName, &testConfig{}), ErrInterpolationCycle)
+}
+
+func TestApiLoadSaveLiteral(t *testing.T) {
+
+	type testConfig struct {
+		Quoted   string `env:"LITERAL_QUOTED" secret:"true"`
+		Unquoted string `env:"LITERAL_UNQUOTED" secret:"true"`
+	}
+
+	requirer := require.New(t)
+	unsetTestEnv(t, "LITERAL_QUOTED", "LITERAL_UNQUOTED")
+	t.Setenv("SWORD", "sword")
+
+	tempDir := t.TempDir()
+	envFileName := filepath.Join(tempDir, "config.env")
+	entries := "LITERAL_QUOTED='pa$SWORD ${SWORD}'\nLITERAL_UNQUOTED=pa$SWORD\n"
+	requirer.NoError(os.WriteFile(envFileName, []byte(entries), 0600))
+	jsonFileName := filepath.Join(tempDir, "config.json")
+	requirer.NoError(os.WriteFile(jsonFileName, []byte(`{"LITERAL_QUOTED": "pa$SWORD ${SWORD}", "LITERAL_UNQUOTED": "pa$SWORD"}`), 0600))
+
+	// single quoted dotenv values are literal, while references within others are expanded
+	config := testConfig{}
+	requirer.NoError(LoadConfig(envFileName, &config))
+	requirer.Equal(testConfig{Quoted: "pa$SWORD ${SWORD}", Unquoted: "pasword"}, config)
+	requirer.NoError(SaveConfig(envFileName, config))
+	loadedConfig := testConfig{}
+	requirer.NoError(LoadConfig(envFileName, &loadedConfig))
+	requirer.Equal(config, loadedConfig)
+
+	// given WithoutInterpolation, values are loaded as written, whatever the format
+	requirer.NoError(os.WriteFile(envFileName, []byte(entries), 0600))
+	for _, fileName := range []string{envFileName, jsonFileName} {
+		config := testConfig{}
+		requirer.NoError(LoadConfig(fileName, &config, WithoutInterpolation()))
+		requirer.Equal(testConfig{Quoted: "pa$SWORD ${SWORD}", Unquoted: "pa$SWORD"}, config, fileName)
+		requirer.NoError(SaveConfig(fileName, config, WithoutInterpolation()))
+		loadedConfig := testConfig{}
+		requirer.NoError(LoadConfig(fileName, &loadedConfig, WithoutInterpolation()))
+		requirer.Equal(config, loadedConfig, fileName)
+	}
+}
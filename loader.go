@@ -1,26 +1,255 @@
 package configurator
 
 import (
+	"bytes"
 	"context"
-	"log"
+	"errors"
+	"io/fs"
+	"os"
+	"reflect"
+	"strings"
 
-	"github.com/joho/godotenv"
 	"github.com/sethvargo/go-envconfig"
 )
 
 // LoadConfig loads uninitialized configuration values from the environment or from
 // 'configFile', applying the defaults as specified in the 'config' structure's tags.
+// The format of 'configFile' is indicated by its extension unless given (see WithFormat).
 // Upon successful return, all environment values on publicly accessible, supported
 // properties of the 'config' structure are loaded both into the config structure
-// and into the environment.
-func LoadConfig[T any](configFile string, config *T) error {
-	if err := godotenv.Load(configFile); err != nil {
-		log.Printf("NOTE: ignored %v\n", err)
+// and into the environment.  Values loaded from a SecretStore (see WithSecretStore)
+// or from flags (see WithFlags) are loaded only into the config structure.  Encrypted
+// envelopes (see WithEncryptionKey) are decrypted only within the config structure, as are
+// values loaded from value files (see WithFileIndirection and WithValuesDir) and from
+// credential helper commands (see the `helper` tag and WithCredentialHelpers).
+// The source of each value can be obtained using WithProvenance.  When values are missing
+// or invalid, a MultiError holding a FieldError for each offending item is returned.  Files having an older
+// version are first upgraded by the given migrations (see WithMigrations).  Entries
+// of the file not corresponding to any item are ignored unless given WithUnknownKeys.
+// A missing file is ignored unless given WithRequiredFile, while a FileError is returned
+// if it can't be read or parsed.
+func LoadConfig[T any](configFile string, config *T, opts ...Option) error {
+	options := newOptions(opts)
+	initialized, getterErr := getInitializedNames(*config)
+	if getterErr != nil {
+		return getterErr
+	}
+
+	if migrateErr := migrateConfigFile(configFile, options); migrateErr != nil {
+		return migrateErr
+	}
+	knownNames, getterErr := getKnownNames(*config, options)
+	if getterErr != nil {
+		return getterErr
+	}
+	if checkErr := checkUnknownKeys(configFile, knownNames, options); checkErr != nil {
+		return checkErr
+	}
+	fileSources, shadowedSources, loadErr := loadConfigFileIntoEnv(configFile, options)
+	if loadErr != nil {
+		if !errors.Is(loadErr, fs.ErrNotExist) || options.requireFile {
+			return loadErr
+		}
+		options.logger.Info("configuration file not found", "file", configFile)
+	}
+	for _, envName := range sortedKeys(shadowedSources) {
+		options.logger.Warn("value is shadowed by the environment", "file", configFile, "env", envName)
+	}
+
+	aliases, aliasesErr := getAliasesByName(*config)
+	if aliasesErr != nil {
+		return aliasesErr
+	}
+	lookuper := &recordingLookuper{aliases: aliases, sources: make(Provenance), values: make(map[string]string), logger: options.logger}
+	if options.flagSet != nil {
+		// values of flags take precedence over all others
+		lookuper.lookupers = append(lookuper.lookupers, flagsLookuper(options.flagSet))
+	}
+	lookuper.lookupers = append(lookuper.lookupers, sourcedLookuper{
+		lookuper: envconfig.OsLookuper(),
+		sourceOf: func(envName string) ValueSource {
+			if fileSource, fromFile := fileSources[envName]; fromFile {
+				return fileSource
+			}
+			if shadowedSource, shadows := shadowedSources[envName]; shadows {
+				return ValueSource{Kind: SourceEnv, Shadowed: &shadowedSource}
+			}
+			return ValueSource{Kind: SourceEnv}
+		},
+	})
+	if options.fileIndirection || options.valuesDir != "" {
+		valueFilesLookuper, valueFileSources, loadErr := loadValueFilesLookuper(*config, options)
+		if loadErr != nil {
+			return loadErr
+		}
+		// values found in the environment take precedence over those in value files
+		lookuper.lookupers = append(lookuper.lookupers, sourcedLookuper{
+			lookuper: valueFilesLookuper,
+			sourceOf: func(envName string) ValueSource {
+				return ValueSource{Kind: SourceValueFile, File: valueFileSources[envName]}
+			},
+		})
+	}
+	helperCommands, helperSources, helperErr := getHelperCommands(*config, configFile, options)
+	if helperErr != nil {
+		return helperErr
+	}
+	helpers := newHelperLookuper(helperCommands, options.helperTimeout)
+	lookuper.lookupers = append(lookuper.lookupers, sourcedLookuper{
+		lookuper: helpers,
+		sourceOf: func(envName string) ValueSource {
+			return helperSources[envName]
+		},
+	})
+	if options.secretStore != nil {
+		secretStoreLookuper, lookuperErr := SecretStoreLookuper(options.secretStore)
+		if lookuperErr != nil {
+			return lookuperErr
+		}
+		// values found in the environment take precedence over those in the secret store
+		lookuper.lookupers = append(lookuper.lookupers, sourcedLookuper{
+			lookuper: secretStoreLookuper,
+			sourceOf: constantSource(ValueSource{Kind: SourceSecretStore}),
+		})
 	}
 
 	ctx := context.Background()
-	if err := envconfig.Process(ctx, config); err != nil {
-		return err
+	processErr := envconfig.ProcessWith(ctx, config, lookuper, options.decryptMutatorFor(lookuper))
+	var fieldErrs []error
+	if processErr != nil {
+		// envconfig stops at the first invalid item; report all of them
+		fieldErrs = getFieldErrors(*config, lookuper, options)
+	}
+	if helperErr := helpers.err(); helperErr != nil {
+		// rather than reporting the values of failed credential helpers as missing
+		return helperErr
+	}
+	if len(fieldErrs) != 0 {
+		return newMultiError(fieldErrs)
+	}
+	if processErr != nil {
+		return processErr
+	}
+	if options.provenance != nil {
+		return fillProvenance(options.provenance, config, lookuper.sources, initialized)
 	}
 	return nil
 }
+
+// loadConfigFileIntoEnv sets the environment variables named in 'configFile' to the values
+// found in the file, returning their sources.  Unless the file is to override the environment
+// (see WithFileOverridingEnv), variables set other than by configurator (e.g., exported by the
+// user's shell) are left unchanged, and the sources of those having different values are
+// returned as shadowed.  References to variables within the values are expanded unless given
+// WithoutInterpolation (see interpolate), and the entries of the file of the selected profile
+// (see WithProfile) take precedence.
+func loadConfigFileIntoEnv(configFile string, options *options) (fileSources, shadowedSources map[string]ValueSource, err error) {
+	fileEnv, entrySources, readErr := readConfigLayers(configFile, options)
+	if readErr != nil {
+		return nil, nil, readErr
+	}
+	fileEnv, interpolateErr := options.interpolate(fileEnv)
+	if interpolateErr != nil {
+		return nil, nil, interpolateErr
+	}
+	fileSources = make(map[string]ValueSource)
+	shadowedSources = make(map[string]ValueSource)
+	for envName, envVal := range fileEnv {
+		if _, isHelper := options.helperCommandOf(envVal); isHelper {
+			// its value is obtained only when loaded into the config structure
+			if _, isExternal := lookupExternalEnv(envName); !isExternal {
+				if unsetErr := unsetManagedEnv(envName); unsetErr != nil {
+					return fileSources, shadowedSources, unsetErr
+				}
+			}
+			continue
+		}
+		fileSource := entrySources[envName]
+		if currentVal, found := os.LookupEnv(envName); found && !options.fileOverridesEnv {
+			if currentVal == envVal {
+				fileSources[envName] = fileSource
+				continue
+			}
+			if _, isExternal := lookupExternalEnv(envName); isExternal {
+				shadowedSources[envName] = fileSource
+				continue
+			}
+			// otherwise, it was set by configurator from an earlier version of the file
+		}
+		if setEnvErr := setManagedEnv(envName, envVal); setEnvErr != nil {
+			return fileSources, shadowedSources, setEnvErr
+		}
+		fileSources[envName] = fileSource
+	}
+	return fileSources, shadowedSources, nil
+}
+
+// readConfigFile returns the configuration entries found in 'configFile'
+func readConfigFile(configFile string, format Format) (map[string]string, error) {
+	contents, readErr := os.ReadFile(configFile)
+	if readErr != nil {
+		return nil, &FileError{File: configFile, Err: readErr}
+	}
+	return parseConfigFile(configFile, contents, format)
+}
+
+// parseConfigFile returns the configuration entries found in 'contents' of 'configFile'
+func parseConfigFile(configFile string, contents []byte, format Format) (map[string]string, error) {
+	fileEnv, parseErr := format.Read(bytes.NewReader(contents))
+	if parseErr != nil {
+		line, cause := locateParseError(contents, parseErr)
+		return nil, &FileError{File: configFile, Line: line, Err: cause}
+	}
+	return fileEnv, nil
+}
+
+// ReadConfigMap reads the map of environment name: environment value entries from 'configFile',
+// in the format indicated by its extension (see FormatForFile) unless given (see WithFormat).
+// Unlike LoadConfig, it doesn't change the environment, nor expand references to variables
+// within the values (e.g., "${HOME}/.local/share/app").
+func ReadConfigMap(configFileName string, opts ...Option) (map[string]any, error) {
+	options := newOptions(opts)
+	fileEnv, readErr := readConfigFile(configFileName, options.formatFor(configFileName))
+	if readErr != nil {
+		return nil, readErr
+	}
+	configMap := make(map[string]any, len(fileEnv))
+	for envName, envVal := range fileEnv {
+		configMap[envName] = envVal
+	}
+	return configMap, nil
+}
+
+// getFieldErrors returns a FieldError for each environment item of 'config' whose value,
+// as looked up by 'lookuper', is missing or invalid.  Each item is loaded in isolation,
+// into a structure holding only its field, so that config itself isn't changed.
+func getFieldErrors[T any](config T, lookuper *recordingLookuper, options *options) []error {
+	cfgStructType, _, getConfigInfoErr := getConfigStructInfo(&config)
+	if getConfigInfoErr != nil {
+		return nil
+	}
+	var fieldErrs []error
+	for fieldIndex := 0; fieldIndex < cfgStructType.NumField(); fieldIndex++ {
+		field := cfgStructType.Field(fieldIndex)
+		if !field.IsExported() {
+			// e.g., private visibility
+			continue
+		}
+		field.Anonymous = false
+		fieldConfig := reflect.New(reflect.StructOf([]reflect.StructField{field}))
+		processErr := envconfig.ProcessWith(context.Background(), fieldConfig.Interface(), lookuper, options.decryptMutatorFor(lookuper))
+		if processErr == nil {
+			continue
+		}
+		envName := strings.Split(field.Tag.Get(envTagKey), ",")[0]
+		if envName == "" {
+			envName = field.Name
+		}
+		if cause := errors.Unwrap(processErr); cause != nil {
+			// without envconfig's prefix naming the field (and repeating its value)
+			processErr = cause
+		}
+		fieldErrs = append(fieldErrs, newFieldError(envName, lookuper.values[envName], field.Tag.Get("secret"), processErr))
+	}
+	return fieldErrs
+}
@@ -1,7 +1,9 @@
 package configurator
 
 import (
+	"io/fs"
 	"os"
+	"path/filepath"
 	"testing"
 
 	"github.com/stretchr/testify/require"
@@ -80,3 +82,82 @@ func TestApiLoadRequired(t *testing.T) {
 	requirer.Equal("also_seen", config.S1B)
 	requirer.Equal("", config.S3)
 }
+
+func TestApiLoadAliases(t *testing.T) {
+
+	type testConfig struct {
+		Host string `env:"ALIAS_HOST" aliases:"ALIAS_HOSTNAME,ALIAS_SERVER"`
+		Port string `env:"ALIAS_PORT,default=80" aliases:"ALIAS_LISTEN_PORT"`
+	}
+
+	requirer := require.New(t)
+	unsetTestEnv(t, "ALIAS_HOST", "ALIAS_HOSTNAME", "ALIAS_SERVER", "ALIAS_PORT", "ALIAS_LISTEN_PORT")
+
+	envFileName, ctefErr := createTempEnvFileFromMap(t, map[string]any{"ALIAS_SERVER": "example.com"})
+	requirer.NoError(ctefErr)
+	t.Setenv("ALIAS_LISTEN_PORT", "8080")
+
+	// values are loaded from deprecated names
+	config := testConfig{}
+	provenance := make(Provenance)
+	requirer.NoError(LoadConfig(envFileName, &config, WithProvenance(provenance)))
+	requirer.Equal(testConfig{Host: "example.com", Port: "8080"}, config)
+	requirer.Equal("file "+envFileName+":1 as ALIAS_SERVER", provenance["ALIAS_HOST"].String())
+	requirer.Equal("env as ALIAS_LISTEN_PORT", provenance["ALIAS_PORT"].String())
+
+	// and saved using the current names
+	requirer.NoError(SaveConfig(envFileName, config))
+	contents, readErr := os.ReadFile(envFileName)
+	requirer.NoError(readErr)
+	requirer.Equal("ALIAS_HOST=example.com\nALIAS_PORT=8080\n", string(contents))
+	_, found := os.LookupEnv("ALIAS_SERVER")
+	requirer.False(found)
+	requirer.Equal("8080", os.Getenv("ALIAS_LISTEN_PORT"))
+
+	// current names take precedence
+	config = testConfig{}
+	requirer.NoError(LoadConfig(envFileName, &config))
+	requirer.Equal("8080", config.Port)
+	provenance = make(Provenance)
+	requirer.NoError(os.Setenv("ALIAS_PORT", "9090"))
+	config = testConfig{}
+	requirer.NoError(LoadConfig(envFileName, &config, WithProvenance(provenance)))
+	requirer.Equal("9090", config.Port)
+	requirer.Equal(SourceEnv, provenance["ALIAS_PORT"].Kind)
+}
+
+func TestApiLoadFileErrors(t *testing.T) {
+
+	type testConfig struct {
+		S1 string `env:"FILE_S1,default=default"`
+	}
+
+	requirer := require.New(t)
+	unsetTestEnv(t, "FILE_S1")
+	tempDir := t.TempDir()
+
+	// a missing file is benign unless required
+	missingFileName := filepath.Join(tempDir, "missing.env")
+	config := testConfig{}
+	requirer.NoError(LoadConfig(missingFileName, &config))
+	requirer.Equal("default", config.S1)
+	loadErr := LoadConfig(missingFileName, &testConfig{}, WithRequiredFile())
+	var fileErr *FileError
+	requirer.ErrorAs(loadErr, &fileErr)
+	requirer.Equal(missingFileName, fileErr.File)
+	requirer.ErrorIs(loadErr, fs.ErrNotExist)
+
+	// a malformed file is reported with the line at fault
+	malformedFileName := filepath.Join(tempDir, "malformed.env")
+	requirer.NoError(os.WriteFile(malformedFileName, []byte("FILE_S1=one\nFILE-S2=two\n"), 0600))
+	loadErr = LoadConfig(malformedFileName, &testConfig{})
+	requirer.ErrorAs(loadErr, &fileErr)
+	requirer.Equal(2, fileErr.Line)
+	requirer.EqualError(loadErr, "can't read("+malformedFileName+":2): unexpected character \"-\" in variable name")
+
+	// as is one which can't be read
+	loadErr = LoadConfig(tempDir, &testConfig{})
+	requirer.ErrorAs(loadErr, &fileErr)
+	requirer.Equal(tempDir, fileErr.File)
+	requirer.NotErrorIs(loadErr, fs.ErrNotExist)
+}
@@ -0,0 +1,270 @@
+package configurator
+
+import (
+	"errors"
+	"fmt"
+	"io/fs"
+	"log/slog"
+	"maps"
+	"os"
+	"path/filepath"
+	"slices"
+	"strconv"
+)
+
+// DefaultVersionKey is the name of the entry holding the version of a configuration
+// file when migrations are given (see WithMigrations), unless changed using WithVersionKey
+const DefaultVersionKey = "CONFIG_VERSION"
+
+// MigrationStep changes the entries of a configuration file (as read by ReadConfigMap)
+// in place; note that values of secrets may be encrypted envelopes (see EncryptValue)
+type MigrationStep func(configMap map[string]any) error
+
+// Migration upgrades a configuration file to Version by applying its Steps, in order
+type Migration struct {
+	Version int
+	Steps   []MigrationStep
+}
+
+// RenameEntry returns a MigrationStep renaming the entry 'oldName' to 'newName'; as encrypted
+// envelopes are bound to their entries (see EncryptValue), 'oldName' should be given as an alias
+// of the item (see the `aliases` tag) if its value may be encrypted
+func RenameEntry(oldName, newName string) MigrationStep {
+	return func(configMap map[string]any) error {
+		if envVal, found := configMap[oldName]; found {
+			delete(configMap, oldName)
+			configMap[newName] = envVal
+		}
+		return nil
+	}
+}
+
+// TransformEntry returns a MigrationStep replacing the value of the entry 'envName'
+// with that returned by 'transform'
+func TransformEntry(envName string, transform func(envVal string) (string, error)) MigrationStep {
+	return func(configMap map[string]any) error {
+		envVal, found := configMap[envName]
+		if !found || envVal == nil {
+			return nil
+		}
+		transformed, transformErr := transform(fmt.Sprintf("%v", envVal))
+		if transformErr != nil {
+			return fmt.Errorf("can't transform(%s): %w", envName, transformErr)
+		}
+		configMap[envName] = transformed
+		return nil
+	}
+}
+
+// DropEntry returns a MigrationStep removing the entry 'envName'
+func DropEntry(envName string) MigrationStep {
+	return func(configMap map[string]any) error {
+		delete(configMap, envName)
+		return nil
+	}
+}
+
+// currentVersion returns the version to which the given migrations upgrade configuration files
+func (o *options) currentVersion() int {
+	version := 0
+	for _, migration := range o.migrations {
+		version = max(version, migration.Version)
+	}
+	return version
+}
+
+// withVersion returns 'configMap', with the current version recorded into a copy of
+// it when migrations are given
+func (o *options) withVersion(configMap map[string]any) map[string]any {
+	if len(o.migrations) == 0 {
+		return configMap
+	}
+	versionedMap := maps.Clone(configMap)
+	versionedMap[o.versionKey] = o.currentVersion()
+	return versionedMap
+}
+
+// migrateConfigFile applies the given migrations newer than the version of 'configFile' to it,
+// and to the files holding the same configuration (i.e., the configuration file of the selected
+// profile, and the files they include), saving the original contents of each into a backup file.
+// The version is recorded only in 'configFile'; if it's missing, nothing is migrated.
+func migrateConfigFile(configFile string, o *options) error {
+	if len(o.migrations) == 0 {
+		return nil
+	}
+	base, readErr := readMigratedFile(configFile, o)
+	if readErr != nil || base == nil {
+		return readErr
+	}
+
+	fileVersion := 0
+	if versionVal, found := base.fileEnv[o.versionKey]; found {
+		var atoiErr error
+		if fileVersion, atoiErr = strconv.Atoi(versionVal); atoiErr != nil {
+			return fmt.Errorf("invalid version(%s) of %s", versionVal, configFile)
+		}
+	}
+	currentVersion := o.currentVersion()
+	if fileVersion > currentVersion {
+		return fmt.Errorf("unsupported version(%d) of %s; newer than %d", fileVersion, configFile, currentVersion)
+	}
+	if fileVersion == currentVersion {
+		return nil
+	}
+
+	// all the files are migrated before any is saved, the base file last, so that the
+	// version is recorded only once all of them are
+	var migratedFiles []*migratedFile
+	migrating := map[string]bool{filepath.Clean(configFile): true}
+	for _, fileName := range watchedFiles(configFile, o) {
+		if migrating[filepath.Clean(fileName)] {
+			continue
+		}
+		migrating[filepath.Clean(fileName)] = true
+		layer, layerErr := readMigratedFile(fileName, o)
+		if layerErr != nil {
+			return layerErr
+		}
+		if layer != nil {
+			migratedFiles = append(migratedFiles, layer)
+		}
+	}
+	migratedFiles = append(migratedFiles, base)
+	for _, mf := range migratedFiles {
+		if migrateErr := mf.migrate(fileVersion, o); migrateErr != nil {
+			return migrateErr
+		}
+	}
+	base.configMap[o.versionKey] = currentVersion
+
+	for _, mf := range migratedFiles {
+		if saveErr := mf.save(fileVersion, o); saveErr != nil {
+			return saveErr
+		}
+		o.logger.Info("migrated configuration file", "file", mf.file, "from", fileVersion, "to", currentVersion, "backup", mf.backupFile(fileVersion))
+	}
+	return nil
+}
+
+// migratedFile is a configuration file being migrated (see migrateConfigFile)
+type migratedFile struct {
+	file      string
+	format    Format
+	contents  []byte
+	fileEnv   map[string]string
+	configMap map[string]any
+}
+
+// readMigratedFile returns 'fileName' to be migrated, or nil if it doesn't exist
+func readMigratedFile(fileName string, o *options) (*migratedFile, error) {
+	contents, readErr := os.ReadFile(fileName)
+	if errors.Is(readErr, fs.ErrNotExist) {
+		return nil, nil
+	}
+	if readErr != nil {
+		return nil, &FileError{File: fileName, Err: readErr}
+	}
+	format := o.formatFor(fileName)
+	fileEnv, parseErr := parseConfigFile(fileName, contents, format)
+	if parseErr != nil {
+		return nil, parseErr
+	}
+	// literal values are saved as such (see templatesOf)
+	fileEnv = o.templatesOf(contents, format, fileEnv)
+	return &migratedFile{file: fileName, format: format, contents: contents, fileEnv: fileEnv}, nil
+}
+
+// migrate applies the given migrations newer than 'fileVersion' to the entries of the file; the
+// version recorded into files other than the base configuration file (e.g., by earlier versions
+// of SaveConfig) is removed
+func (mf *migratedFile) migrate(fileVersion int, o *options) error {
+	mf.configMap = make(map[string]any, len(mf.fileEnv))
+	for envName, envVal := range mf.fileEnv {
+		mf.configMap[envName] = envVal
+	}
+	delete(mf.configMap, o.versionKey)
+	migrations := slices.Clone(o.migrations)
+	slices.SortStableFunc(migrations, func(m1, m2 Migration) int {
+		return m1.Version - m2.Version
+	})
+	for _, migration := range migrations {
+		if migration.Version <= fileVersion {
+			continue
+		}
+		for _, step := range migration.Steps {
+			if stepErr := step(mf.configMap); stepErr != nil {
+				return fmt.Errorf("can't migrate(%s) to version(%d): %w", mf.file, migration.Version, stepErr)
+			}
+		}
+	}
+	return nil
+}
+
+// backupFile returns the name of the file holding the contents of the file as of 'fileVersion'
+func (mf *migratedFile) backupFile(fileVersion int) string {
+	return fmt.Sprintf("%s.v%d.bak", mf.file, fileVersion)
+}
+
+// save saves the migrated entries into the file, its original contents into its backup file
+func (mf *migratedFile) save(fileVersion int, o *options) error {
+	if writeErr := os.WriteFile(mf.backupFile(fileVersion), mf.contents, 0600); writeErr != nil {
+		return fmt.Errorf("can't back up(%s): %w", mf.file, writeErr)
+	}
+	if writeErr := writeConfigFile(mf.file, mf.configMap, mf.format, o.logger); writeErr != nil {
+		return writeErr
+	}
+	for envName := range mf.fileEnv {
+		if _, kept := mf.configMap[envName]; kept {
+			continue
+		}
+		// values loaded from entries renamed or dropped by migrations no longer apply
+		if _, isExternal := lookupExternalEnv(envName); !isExternal {
+			if unsetErr := unsetManagedEnv(envName); unsetErr != nil {
+				return unsetErr
+			}
+		}
+	}
+	return nil
+}
+
+// recordVersion records the current version into 'configFile' (see WithMigrations), creating it
+// if missing, unless already recorded there
+func (o *options) recordVersion(configFile string) error {
+	if len(o.migrations) == 0 {
+		return nil
+	}
+	mf, readErr := readMigratedFile(configFile, o)
+	if readErr != nil {
+		return readErr
+	}
+	if mf == nil {
+		mf = &migratedFile{file: configFile, format: o.formatFor(configFile), fileEnv: map[string]string{}}
+	}
+	if mf.fileEnv[o.versionKey] == strconv.Itoa(o.currentVersion()) {
+		return nil
+	}
+	configMap := make(map[string]any, len(mf.fileEnv)+1)
+	for envName, envVal := range mf.fileEnv {
+		configMap[envName] = envVal
+	}
+	return writeConfigFile(configFile, o.withVersion(configMap), mf.format, o.logger)
+}
+
+// writeConfigFile replaces the contents of 'configFile' with the entries of 'configMap'
+// written in 'format', without changing the environment
+func writeConfigFile(configFile string, configMap map[string]any, format Format, logger *slog.Logger) error {
+	directiveTexts := readIncludeDirectives(configFile, format)
+	file, openErr := os.OpenFile(configFile, os.O_CREATE|os.O_WRONLY|os.O_TRUNC, 0600)
+	if openErr != nil {
+		return openErr
+	}
+	defer func() {
+		if closeErr := file.Close(); closeErr != nil {
+			logger.Warn("error closing configuration file", "file", configFile, "error", closeErr)
+		}
+	}()
+	if writeErr := writeIncludeDirectives(file, directiveTexts); writeErr != nil {
+		return writeErr
+	}
+	return format.Write(file, configMap)
+}
@@ -0,0 +1,137 @@
+package configurator
+
+import (
+	"errors"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+
+	"github.com/stretchr/testify/require"
+)
+
+func TestApiLoadWithMigrations(t *testing.T) {
+
+	type testConfig struct {
+		Timeout string `env:"MIG_TIMEOUT"`
+		Host    string `env:"MIG_HOST"`
+	}
+
+	requirer := require.New(t)
+	unsetTestEnv(t, "MIG_TIMEOUT", "MIG_HOST", "MIG_HOSTNAME", "MIG_OBSOLETE", "CONFIG_VERSION")
+
+	migrations := []Migration{
+		{Version: 2, Steps: []MigrationStep{
+			TransformEntry("MIG_TIMEOUT", func(seconds string) (string, error) {
+				return seconds + "s", nil
+			}),
+		}},
+		{Version: 1, Steps: []MigrationStep{
+			RenameEntry("MIG_HOSTNAME", "MIG_HOST"),
+			DropEntry("MIG_OBSOLETE"),
+		}},
+	}
+
+	envFileName := filepath.Join(t.TempDir(), "config.env")
+	const original = "MIG_HOSTNAME=example.com\nMIG_OBSOLETE=x\nMIG_TIMEOUT=30\n"
+	requirer.NoError(os.WriteFile(envFileName, []byte(original), 0600))
+
+	config := testConfig{}
+	requirer.NoError(LoadConfig(envFileName, &config, WithMigrations(migrations...)))
+	requirer.Equal(testConfig{Timeout: "30s", Host: "example.com"}, config)
+
+	contents, readErr := os.ReadFile(envFileName)
+	requirer.NoError(readErr)
+	requirer.Equal("CONFIG_VERSION=2\nMIG_HOST=example.com\nMIG_TIMEOUT=30s\n", string(contents))
+	backup, backupErr := os.ReadFile(envFileName + ".v0.bak")
+	requirer.NoError(backupErr)
+	requirer.Equal(original, string(backup))
+
+	// an up-to-date file isn't migrated again
+	config = testConfig{}
+	requirer.NoError(LoadConfig(envFileName, &config, WithMigrations(migrations...)))
+	requirer.Equal(testConfig{Timeout: "30s", Host: "example.com"}, config)
+
+	// the version is saved
+	requirer.NoError(SaveConfig(envFileName, testConfig{Timeout: "1m", Host: "h"}, WithMigrations(migrations...)))
+	contents, readErr = os.ReadFile(envFileName)
+	requirer.NoError(readErr)
+	requirer.Equal("CONFIG_VERSION=2\nMIG_HOST=h\nMIG_TIMEOUT=1m\n", string(contents))
+	changes, diffErr := DiffConfigFile(envFileName, testConfig{Timeout: "1m", Host: "h"}, WithMigrations(migrations...))
+	requirer.NoError(diffErr)
+	requirer.Empty(changes)
+
+	// files of newer versions aren't loaded
+	requirer.ErrorContains(LoadConfig(envFileName, &config, WithMigrations(migrations[1])), "unsupported version(2)")
+}
+
+func TestApiLoadWithMigrationsLayered(t *testing.T) {
+
+	type testConfig struct {
+		Host  string `env:"MIGL_HOST"`
+		Level string `env:"MIGL_LEVEL"`
+	}
+
+	requirer := require.New(t)
+	unsetTestEnv(t, "MIGL_HOST", "MIGL_HOSTNAME", "MIGL_LEVEL", "MIGL_LOG_LEVEL", "CONFIG_VERSION")
+
+	migrations := WithMigrations(Migration{Version: 1, Steps: []MigrationStep{
+		RenameEntry("MIGL_HOSTNAME", "MIGL_HOST"),
+		RenameEntry("MIGL_LOG_LEVEL", "MIGL_LEVEL"),
+	}})
+	tempDir := t.TempDir()
+	envFileName := filepath.Join(tempDir, "config.env")
+	commonFileName := filepath.Join(tempDir, "common.env")
+	prodFileName := ProfileFile(envFileName, "prod")
+	requirer.NoError(os.WriteFile(envFileName, []byte("#include common.env\nMIGL_HOSTNAME=base\n"), 0600))
+	requirer.NoError(os.WriteFile(commonFileName, []byte("MIGL_LOG_LEVEL=info\n"), 0600))
+	requirer.NoError(os.WriteFile(prodFileName, []byte("MIGL_HOSTNAME=prod\n"), 0600))
+
+	// the files of the profile and included files are migrated along with the base file
+	config := testConfig{}
+	requirer.NoError(LoadConfig(envFileName, &config, migrations, WithProfile("prod"), WithLogger(nil)))
+	requirer.Equal(testConfig{Host: "prod", Level: "info"}, config)
+	for fileName, expectedContents := range map[string]string{
+		envFileName:    "#include common.env\nCONFIG_VERSION=1\nMIGL_HOST=base\n",
+		commonFileName: "MIGL_LEVEL=info\n",
+		prodFileName:   "MIGL_HOST=prod\n",
+	} {
+		contents, readErr := os.ReadFile(fileName)
+		requirer.NoError(readErr)
+		requirer.Equal(expectedContents, string(contents), fileName)
+		_, backupErr := os.Stat(fileName + ".v0.bak")
+		requirer.NoError(backupErr)
+	}
+
+	// the version is recorded only in the base file
+	requirer.NoError(SaveConfig(envFileName, testConfig{Host: "prod2", Level: "info"}, migrations, WithProfile("prod"), WithLogger(nil)))
+	contents, readErr := os.ReadFile(prodFileName)
+	requirer.NoError(readErr)
+	requirer.Equal("MIGL_HOST=prod2\n", string(contents))
+	contents, readErr = os.ReadFile(envFileName)
+	requirer.NoError(readErr)
+	requirer.Equal("#include common.env\nCONFIG_VERSION=1\nMIGL_HOST=base\n", string(contents))
+}
+
+func TestMigrationErrors(t *testing.T) {
+
+	requirer := require.New(t)
+
+	envFileName := filepath.Join(t.TempDir(), "config.env")
+	requirer.NoError(os.WriteFile(envFileName, []byte("MIGE_X=x\n"), 0600))
+
+	failing := Migration{Version: 1, Steps: []MigrationStep{
+		TransformEntry("MIGE_X", func(string) (string, error) {
+			return "", errors.New("bad value")
+		}),
+	}}
+	requirer.ErrorContains(migrateConfigFile(envFileName, newOptions([]Option{WithMigrations(failing)})),
+		"can't migrate("+envFileName+") to version(1): can't transform(MIGE_X): bad value")
+
+	requirer.NoError(os.WriteFile(envFileName, []byte("VERSION=one\n"), 0600))
+	migrateErr := migrateConfigFile(envFileName, newOptions([]Option{WithMigrations(failing), WithVersionKey("VERSION")}))
+	requirer.True(strings.HasPrefix(migrateErr.Error(), "invalid version(one)"), migrateErr.Error())
+
+	// missing files aren't migrated
+	requirer.NoError(migrateConfigFile(filepath.Join(t.TempDir(), "missing.env"), newOptions([]Option{WithMigrations(failing)})))
+}
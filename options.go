@@ -0,0 +1,274 @@
+package configurator
+
+import (
+	"context"
+	"flag"
+	"fmt"
+	"log/slog"
+	"os"
+	"time"
+)
+
+// Option customizes the behavior of the configurator APIs accepting it
+type Option func(*options)
+
+// options holds the settings established by the Option values passed to an API
+type options struct {
+	secretStore   SecretStore
+	encryptionKey func() ([]byte, error)
+	watchInterval time.Duration
+	format        Format
+	provenance    Provenance
+	flagSet       *flag.FlagSet
+	migrations    []Migration
+	versionKey    string
+	unknownKeys   UnknownKeyPolicy
+	logger        *slog.Logger
+	requireFile   bool
+	valuesDir     string
+	helperTimeout time.Duration
+	profile       string
+	profileEnv    string
+	backups       int
+
+	fileOverridesEnv  bool
+	fileIndirection   bool
+	credentialHelpers bool
+	literalValues     bool
+}
+
+// newOptions returns the settings established by applying 'opts' over the defaults
+func newOptions(opts []Option) *options {
+	o := &options{
+		watchInterval: defaultWatchInterval,
+		versionKey:    DefaultVersionKey,
+		helperTimeout: DefaultHelperTimeout,
+		profileEnv:    DefaultProfileEnv,
+		logger:        slog.Default(),
+	}
+	for _, opt := range opts {
+		opt(o)
+	}
+	return o
+}
+
+// WithSecretStore directs the values of items tagged as `secret` to be saved into,
+// and loaded from 'store' rather than the configuration file
+func WithSecretStore(store SecretStore) Option {
+	return func(o *options) {
+		o.secretStore = store
+	}
+}
+
+// WithEncryptionKey directs the values of items tagged as `secret` to be saved into the
+// configuration file as encrypted envelopes (e.g., "enc:v1:..."), and allows encrypted
+// envelopes to be loaded from it, using 'key' (see GenerateEncryptionKey)
+func WithEncryptionKey(key []byte) Option {
+	return func(o *options) {
+		o.encryptionKey = func() ([]byte, error) {
+			return key, nil
+		}
+	}
+}
+
+// WithEncryptionKeyFile is like WithEncryptionKey, using the key held in 'keyFileName'
+// in the format returned by GenerateEncryptionKey
+func WithEncryptionKeyFile(keyFileName string) Option {
+	return func(o *options) {
+		o.encryptionKey = func() ([]byte, error) {
+			encodedKey, readErr := os.ReadFile(keyFileName)
+			if readErr != nil {
+				return nil, readErr
+			}
+			return decodeEncryptionKey(string(encodedKey))
+		}
+	}
+}
+
+// WithEncryptionKeyEnv is like WithEncryptionKey, using the key held in the environment
+// variable 'envName' in the format returned by GenerateEncryptionKey
+func WithEncryptionKeyEnv(envName string) Option {
+	return func(o *options) {
+		o.encryptionKey = func() ([]byte, error) {
+			encodedKey, found := os.LookupEnv(envName)
+			if !found {
+				return nil, fmt.Errorf("encryption key variable(%s) not set", envName)
+			}
+			return decodeEncryptionKey(encodedKey)
+		}
+	}
+}
+
+// WithWatchInterval sets how often WatchConfig checks the configuration file for changes
+func WithWatchInterval(interval time.Duration) Option {
+	return func(o *options) {
+		o.watchInterval = interval
+	}
+}
+
+// WithFormat overrides the format of the configuration file otherwise indicated
+// by its extension (see FormatForFile)
+func WithFormat(format Format) Option {
+	return func(o *options) {
+		o.format = format
+	}
+}
+
+// WithProvenance directs LoadConfig to record the source of each of the values it loads
+// into 'provenance' (which must not be nil), keyed by environment name
+func WithProvenance(provenance Provenance) Option {
+	return func(o *options) {
+		o.provenance = provenance
+	}
+}
+
+// WithFlags directs LoadConfig to load the values of the flags explicitly set in 'flagSet'
+// (which must already be parsed), taking precedence over values from all other sources.
+// Flags are matched to environment names ignoring case and treating '-' as '_' (e.g., the
+// value of "-conversion-rate" is loaded into the item named "CONVERSION_RATE").
+func WithFlags(flagSet *flag.FlagSet) Option {
+	return func(o *options) {
+		o.flagSet = flagSet
+	}
+}
+
+// WithFileOverridingEnv directs LoadConfig to load the values found in the configuration
+// file into the environment even when they're already set there (e.g., exported by the
+// user's shell), rather than leaving the environment values to shadow them
+func WithFileOverridingEnv() Option {
+	return func(o *options) {
+		o.fileOverridesEnv = true
+	}
+}
+
+// WithoutInterpolation directs LoadConfig to load the values of the configuration file as
+// written, rather than expanding the references to variables within them (e.g.,
+// "${HOME}/.local/share/app"; see interpolate), and SaveConfig to save values as given
+func WithoutInterpolation() Option {
+	return func(o *options) {
+		o.literalValues = true
+	}
+}
+
+// WithMigrations directs SaveConfig and SaveConfigMap to record the version of the configuration
+// file (i.e., the highest Version of 'migrations') into it, and LoadConfig to first upgrade a file
+// having an older version (or none) by applying the newer 'migrations' in order of their versions,
+// along with the configuration file of the selected profile (see WithProfile) and the files they
+// include, whose version is that recorded in the base configuration file.  The upgraded files
+// are saved, with their original contents saved alongside them (e.g., as "config.env.v1.bak").
+func WithMigrations(migrations ...Migration) Option {
+	return func(o *options) {
+		o.migrations = append(o.migrations, migrations...)
+	}
+}
+
+// WithVersionKey sets the name of the entry recording the version of the configuration
+// file (see WithMigrations) to 'versionKey', rather than DefaultVersionKey
+func WithVersionKey(versionKey string) Option {
+	return func(o *options) {
+		o.versionKey = versionKey
+	}
+}
+
+// WithUnknownKeys sets how LoadConfig treats entries of the configuration file which don't
+// correspond to any environment item of the configuration structure (e.g., misspelled names),
+// which are otherwise ignored; see UnknownKeyError
+func WithUnknownKeys(policy UnknownKeyPolicy) Option {
+	return func(o *options) {
+		o.unknownKeys = policy
+	}
+}
+
+// WithLogger directs the notes (e.g., about values shadowed by the environment or loaded
+// from deprecated names) otherwise logged using slog.Default to 'logger', or discards them
+// if 'logger' is nil
+func WithLogger(logger *slog.Logger) Option {
+	return func(o *options) {
+		if logger == nil {
+			logger = slog.New(discardHandler{})
+		}
+		o.logger = logger
+	}
+}
+
+// discardHandler is a slog.Handler discarding all records
+type discardHandler struct{}
+
+func (discardHandler) Enabled(context.Context, slog.Level) bool  { return false }
+func (discardHandler) Handle(context.Context, slog.Record) error { return nil }
+func (dh discardHandler) WithAttrs([]slog.Attr) slog.Handler     { return dh }
+func (dh discardHandler) WithGroup(string) slog.Handler          { return dh }
+
+// WithRequiredFile directs LoadConfig to fail with a FileError if the configuration file
+// doesn't exist, rather than loading values only from other sources
+func WithRequiredFile() Option {
+	return func(o *options) {
+		o.requireFile = true
+	}
+}
+
+// WithFileIndirection directs LoadConfig to load the value of an item missing from both the
+// environment and the configuration file from the file named by the variable having the name
+// of the item followed by FileIndirectionSuffix (e.g., "DB_PASSWORD_FILE=/run/secrets/db_password"),
+// and SaveConfig to leave both the file and the indirection to it intact, rather than saving the value
+// into the configuration file
+func WithFileIndirection() Option {
+	return func(o *options) {
+		o.fileIndirection = true
+	}
+}
+
+// WithValuesDir directs LoadConfig to load the value of an item missing from both the environment
+// and the configuration file from the file within 'dir' having the name of the item (e.g., as
+// mounted by Kubernetes from a Secret or ConfigMap), and SaveConfig to leave the file intact rather
+// than saving the value into the configuration file
+func WithValuesDir(dir string) Option {
+	return func(o *options) {
+		o.valuesDir = dir
+	}
+}
+
+// WithCredentialHelpers allows the values of entries of the configuration file to be obtained
+// by LoadConfig from the output of credential helper commands, e.g. "API_KEY=$(pass show app/key)";
+// SaveConfig keeps such entries rather than saving their values
+func WithCredentialHelpers() Option {
+	return func(o *options) {
+		o.credentialHelpers = true
+	}
+}
+
+// WithHelperTimeout limits how long each credential helper command (see WithCredentialHelpers
+// and the `helper` tag) may run, rather than DefaultHelperTimeout
+func WithHelperTimeout(timeout time.Duration) Option {
+	return func(o *options) {
+		o.helperTimeout = timeout
+	}
+}
+
+// WithProfile directs LoadConfig to load the configuration file of 'profile' (see ProfileFile)
+// layered over the base configuration file, and SaveConfig to save into it only the entries
+// whose values differ from those of the base configuration file.  Unless given, the profile
+// is selected by the flag (see WithFlags) or environment variable named DefaultProfileEnv
+// (e.g., "-config-profile=prod" or "CONFIG_PROFILE=prod"), if set.
+func WithProfile(profile string) Option {
+	return func(o *options) {
+		o.profile = profile
+	}
+}
+
+// WithProfileEnv changes the name of the environment variable (and corresponding flag)
+// selecting the profile (see WithProfile) from DefaultProfileEnv to 'envName'
+func WithProfileEnv(envName string) Option {
+	return func(o *options) {
+		o.profileEnv = envName
+	}
+}
+
+// WithBackups directs SaveConfig and SaveConfigMap to keep the last 'count' versions of each
+// configuration file they replace as timestamped backups next to it (see ListBackups), which
+// may be compared with it (see DiffBackup) and restored (see RestoreBackup)
+func WithBackups(count int) Option {
+	return func(o *options) {
+		o.backups = count
+	}
+}
@@ -0,0 +1,38 @@
+package configurator
+
+import (
+	"bytes"
+	"log/slog"
+	"testing"
+
+	"github.com/stretchr/testify/require"
+)
+
+func TestWithLogger(t *testing.T) {
+
+	type testConfig struct {
+		S1 string `env:"LOGGER_S1"`
+	}
+
+	requirer := require.New(t)
+
+	envFileName, ctefErr := createTempEnvFileFromMap(t, map[string]any{"LOGGER_S1": "from file"})
+	requirer.NoError(ctefErr)
+	t.Setenv("LOGGER_S1", "exported")
+
+	// notes are logged using slog.Default unless directed elsewhere
+	logBuffer := &bytes.Buffer{}
+	defaultLogger := slog.Default()
+	slog.SetDefault(slog.New(slog.NewTextHandler(logBuffer, nil)))
+	defer slog.SetDefault(defaultLogger)
+
+	config := testConfig{}
+	requirer.NoError(LoadConfig(envFileName, &config))
+	requirer.Contains(logBuffer.String(), "env=LOGGER_S1")
+
+	// or discarded
+	logBuffer.Reset()
+	requirer.NoError(LoadConfig(envFileName, &config, WithLogger(nil)))
+	requirer.NoError(SetConfigEnvItem(&config, "LOGGER_S1", "set", WithLogger(nil)))
+	requirer.Empty(logBuffer.String())
+}
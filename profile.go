@@ -0,0 +1,117 @@
+package configurator
+
+import (
+	"errors"
+	"flag"
+	"fmt"
+	"io/fs"
+	"os"
+	"path/filepath"
+	"strings"
+)
+
+// DefaultProfileEnv is the name of the environment variable selecting the profile whose
+// configuration file is layered over the base configuration file (see WithProfile),
+// unless changed using WithProfileEnv
+const DefaultProfileEnv = "CONFIG_PROFILE"
+
+// ProfileFile returns the name of the file holding the configuration of 'profile', layered
+// over that in 'configFile', e.g., "config.prod.env" for "config.env" (or ".env.prod" for ".env")
+func ProfileFile(configFile, profile string) string {
+	ext := filepath.Ext(configFile)
+	if ext == filepath.Base(configFile) {
+		// e.g., ".env"
+		return configFile + "." + profile
+	}
+	return strings.TrimSuffix(configFile, ext) + "." + profile + ext
+}
+
+// profileName returns the selected profile, if any: that given by WithProfile, otherwise
+// that given by the flag (see WithFlags) or the environment variable named as established
+// by WithProfileEnv
+func (o *options) profileName() string {
+	if o.profile != "" {
+		return o.profile
+	}
+	if o.flagSet != nil {
+		var flagProfile string
+		o.flagSet.Visit(func(f *flag.Flag) {
+			if strings.ToUpper(strings.ReplaceAll(f.Name, "-", "_")) == o.profileEnv {
+				flagProfile = f.Value.String()
+			}
+		})
+		if flagProfile != "" {
+			return flagProfile
+		}
+	}
+	return os.Getenv(o.profileEnv)
+}
+
+// configFiles returns the names of the files holding the configuration: 'configFile', followed
+// by the file of the selected profile, if any
+func (o *options) configFiles(configFile string) []string {
+	if profile := o.profileName(); profile != "" {
+		return []string{configFile, ProfileFile(configFile, profile)}
+	}
+	return []string{configFile}
+}
+
+// readConfigLayers returns the entries of 'configFile' overlaid by those of the file of
+// the selected profile, if any, together with their sources.  A missing profile file is
+// ignored, as is a missing 'configFile' if the profile file exists (unless given
+// WithRequiredFile).
+func readConfigLayers(configFile string, o *options) (map[string]string, map[string]ValueSource, error) {
+	fileEnv, fileSources, readErr := readConfigFileEntries(configFile, o)
+	profile := o.profileName()
+	if profile == "" || (readErr != nil && (o.requireFile || !errors.Is(readErr, fs.ErrNotExist))) {
+		return fileEnv, fileSources, readErr
+	}
+
+	profileFile := ProfileFile(configFile, profile)
+	profileEnv, profileSources, profileErr := readConfigFileEntries(profileFile, o)
+	if errors.Is(profileErr, fs.ErrNotExist) {
+		o.logger.Info("profile configuration file not found", "file", profileFile, "profile", profile)
+		return fileEnv, fileSources, readErr
+	}
+	if profileErr != nil {
+		return nil, nil, profileErr
+	}
+	if readErr != nil {
+		o.logger.Info("configuration file not found", "file", configFile)
+		fileEnv, fileSources = make(map[string]string), make(map[string]ValueSource)
+	}
+	for envName, envVal := range profileEnv {
+		fileEnv[envName] = envVal
+		profileSource := profileSources[envName]
+		profileSource.Profile = profile
+		fileSources[envName] = profileSource
+	}
+	return fileEnv, fileSources, nil
+}
+
+// readConfigFileEntries returns the entries of 'configFile', including those of the files
+// it includes, together with their sources
+func readConfigFileEntries(configFile string, o *options) (map[string]string, map[string]ValueSource, error) {
+	return readIncludingEntries(configFile, o, nil)
+}
+
+// profileOverrides returns the entries of 'configMap' to be saved into the file of the selected
+// profile: those whose values differ from the entries of 'configFile' (the base configuration
+// file, including those of the files it includes), omitting those having nil values
+func profileOverrides(configFile string, configMap map[string]any, o *options) map[string]any {
+	baseEnv, _, readErr := readConfigFileEntries(configFile, o)
+	if readErr != nil {
+		baseEnv = map[string]string{}
+	}
+	overrides := make(map[string]any)
+	for envName, envVal := range configMap {
+		if envVal == nil {
+			continue
+		}
+		if baseVal, inBase := baseEnv[envName]; inBase && baseVal == fmt.Sprintf("%v", envVal) {
+			continue
+		}
+		overrides[envName] = envVal
+	}
+	return overrides
+}
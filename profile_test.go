@@ -0,0 +1,92 @@
+package configurator
+
+import (
+	"flag"
+	"os"
+	"path/filepath"
+	"testing"
+
+	"github.com/manifoldco/promptui"
+	"github.com/stretchr/testify/require"
+)
+
+func TestProfileFile(t *testing.T) {
+
+	testCases := []struct {
+		configFile          string
+		expectedProfileFile string
+	}{
+		{"config.env", "config.prod.env"},
+		{filepath.Join("etc", "config.yaml"), filepath.Join("etc", "config.prod.yaml")},
+		{"config", "config.prod"},
+		{filepath.Join("app", ".env"), filepath.Join("app", ".env.prod")},
+	}
+	for _, testCase := range testCases {
+		t.Run(testCase.configFile, func(t *testing.T) {
+			require.Equal(t, testCase.expectedProfileFile, ProfileFile(testCase.configFile, "prod"))
+		})
+	}
+}
+
+func TestApiLoadSaveProfile(t *testing.T) {
+
+	type testConfig struct {
+		Host  string `env:"PROFILE_HOST"`
+		Level string `env:"PROFILE_LEVEL"`
+	}
+
+	requirer := require.New(t)
+	unsetTestEnv(t, "PROFILE_HOST", "PROFILE_LEVEL", DefaultProfileEnv)
+
+	tempDir := t.TempDir()
+	envFileName := filepath.Join(tempDir, "config.env")
+	prodFileName := filepath.Join(tempDir, "config.prod.env")
+	requirer.NoError(os.WriteFile(envFileName, []byte("PROFILE_HOST=localhost\nPROFILE_LEVEL=debug\n"), 0600))
+	requirer.NoError(os.WriteFile(prodFileName, []byte("PROFILE_LEVEL=info\n"), 0600))
+
+	// without a profile, only the base file is loaded
+	config := testConfig{}
+	requirer.NoError(LoadConfig(envFileName, &config))
+	requirer.Equal(testConfig{Host: "localhost", Level: "debug"}, config)
+
+	// the profile's file is layered over the base file
+	t.Setenv(DefaultProfileEnv, "prod")
+	config = testConfig{}
+	provenance := make(Provenance)
+	requirer.NoError(LoadConfig(envFileName, &config, WithProvenance(provenance)))
+	requirer.Equal(testConfig{Host: "localhost", Level: "info"}, config)
+	requirer.Equal(ValueSource{Kind: SourceFile, File: prodFileName, Line: 1, Profile: "prod"}, provenance["PROFILE_LEVEL"])
+	requirer.Equal("", provenance["PROFILE_HOST"].Profile)
+
+	// and shown by the editor
+	seam := &promptUiTestSeam{pr: mockPr{mockedResponses: map[int]string{0: "localhost", 1: "info", 2: "y"}}}
+	requirer.NoError(editConfig(&config, seam, 1, WithProvenance(provenance)))
+	requirer.Equal("PROFILE_HOST", seam.prompters[0].(*promptui.Prompt).Label)
+	requirer.Equal("PROFILE_LEVEL (profile prod)", seam.prompters[1].(*promptui.Prompt).Label)
+
+	// only values differing from the base file are saved, into the profile's file
+	config.Host = "example.com"
+	requirer.NoError(SaveConfig(envFileName, config))
+	contents, readErr := os.ReadFile(prodFileName)
+	requirer.NoError(readErr)
+	requirer.Equal("PROFILE_HOST=example.com\nPROFILE_LEVEL=info\n", string(contents))
+	config.Level = "debug"
+	requirer.NoError(SaveConfig(envFileName, config))
+	contents, readErr = os.ReadFile(prodFileName)
+	requirer.NoError(readErr)
+	requirer.Equal("PROFILE_HOST=example.com\n", string(contents))
+	contents, readErr = os.ReadFile(envFileName)
+	requirer.NoError(readErr)
+	requirer.Equal("PROFILE_HOST=localhost\nPROFILE_LEVEL=debug\n", string(contents))
+
+	// the profile given by an option or flag takes precedence; a missing profile file is ignored
+	flagSet := flag.NewFlagSet("test", flag.ContinueOnError)
+	flagSet.String("config-profile", "", "")
+	requirer.NoError(flagSet.Parse([]string{"-config-profile=staging"}))
+	config = testConfig{}
+	requirer.NoError(LoadConfig(envFileName, &config, WithFlags(flagSet)))
+	requirer.Equal(testConfig{Host: "localhost", Level: "debug"}, config)
+	config = testConfig{}
+	requirer.NoError(LoadConfig(envFileName, &config, WithFlags(flagSet), WithProfile("prod")))
+	requirer.Equal(testConfig{Host: "example.com", Level: "debug"}, config)
+}
@@ -0,0 +1,194 @@
+package configurator
+
+import (
+	"flag"
+	"fmt"
+	"log/slog"
+	"reflect"
+	"strings"
+
+	"github.com/sethvargo/go-envconfig"
+)
+
+// SourceKind identifies the kind of source a loaded configuration value came from
+type SourceKind string
+
+const (
+	SourceNone             SourceKind = "none"              // no value was loaded
+	SourceInitial          SourceKind = "initial"           // the value was set before loading
+	SourceDefault          SourceKind = "default"           // from the `default` option of the `env` tag
+	SourceFile             SourceKind = "file"              // from the configuration file
+	SourceEnv              SourceKind = "env"               // from a variable set in the environment
+	SourceSecretStore      SourceKind = "secret store"      // from the SecretStore (see WithSecretStore)
+	SourceFlag             SourceKind = "flag"              // from a command line flag (see WithFlags)
+	SourceValueFile        SourceKind = "value file"        // from a file holding only the value (see WithFileIndirection and WithValuesDir)
+	SourceCredentialHelper SourceKind = "credential helper" // from the output of a credential helper command (see the `helper` tag and WithCredentialHelpers)
+)
+
+// ValueSource describes where a loaded configuration value came from
+type ValueSource struct {
+	Kind SourceKind
+	File string // name of the file holding the value, if from a file
+	Line int    // line number of the value within File, if known
+	Flag string // name of the flag, if from a flag
+	// Alias is the deprecated name (see the `aliases` tag) under which the value was found, if any
+	Alias string
+	// Profile is the profile (see WithProfile) whose configuration file held the value, if any
+	Profile string
+	// Shadowed is the source of the value in the configuration file which was
+	// overridden by this value from the environment, if any
+	Shadowed *ValueSource
+}
+
+// String describes the source, e.g., "file config.env:3", "flag -rate" or "env"
+func (vs ValueSource) String() string {
+	if vs.Alias != "" {
+		aliasedSource := vs
+		aliasedSource.Alias = ""
+		return fmt.Sprintf("%s as %s", aliasedSource, vs.Alias)
+	}
+	switch {
+	case vs.Shadowed != nil:
+		return fmt.Sprintf("%s (shadowing %s)", vs.Kind, vs.Shadowed)
+	case vs.File != "" && vs.Line > 0:
+		return fmt.Sprintf("%s %s:%d", vs.Kind, vs.File, vs.Line)
+	case vs.File != "":
+		return fmt.Sprintf("%s %s", vs.Kind, vs.File)
+	case vs.Flag != "":
+		return fmt.Sprintf("%s -%s", vs.Kind, vs.Flag)
+	default:
+		return string(vs.Kind)
+	}
+}
+
+// Provenance maps environment names to the sources of their loaded values (see WithProvenance)
+type Provenance map[string]ValueSource
+
+// sourcedLookuper is an envconfig.Lookuper whose values come from a particular source
+type sourcedLookuper struct {
+	lookuper envconfig.Lookuper
+	sourceOf func(envName string) ValueSource
+}
+
+// recordingLookuper is an envconfig.Lookuper returning the value of the first of its
+// lookupers finding one, and recording its source.  When none is found, the deprecated
+// 'aliases' of the environment name are looked up in turn.
+type recordingLookuper struct {
+	lookupers []sourcedLookuper
+	aliases   map[string][]string
+	sources   Provenance
+	values    map[string]string
+	logger    *slog.Logger
+}
+
+func (rl *recordingLookuper) Lookup(envName string) (string, bool) {
+	for _, lookupName := range append([]string{envName}, rl.aliases[envName]...) {
+		for _, sl := range rl.lookupers {
+			if envVal, found := sl.lookuper.Lookup(lookupName); found {
+				source := sl.sourceOf(lookupName)
+				if lookupName != envName {
+					source.Alias = lookupName
+					if _, alreadyFound := rl.sources[envName]; !alreadyFound {
+						rl.logger.Warn("deprecated name; use the current name instead", "env", envName, "alias", lookupName, "source", source.String())
+					}
+				}
+				rl.sources[envName] = source
+				rl.values[envName] = envVal
+				return envVal, true
+			}
+		}
+	}
+	return "", false
+}
+
+// getAliasesByName returns the deprecated aliases of the environment items of 'config', by name
+func getAliasesByName[T any](config T) (map[string][]string, error) {
+	envItems, getterErr := GetConfigEnvItems(config)
+	if getterErr != nil {
+		return nil, getterErr
+	}
+	aliases := make(map[string][]string)
+	for _, envItem := range envItems {
+		if len(envItem.Aliases) != 0 {
+			aliases[envItem.Name] = envItem.Aliases
+		}
+	}
+	return aliases, nil
+}
+
+// constantSource returns a function giving 'source' as the source of any value
+func constantSource(source ValueSource) func(string) ValueSource {
+	return func(string) ValueSource {
+		return source
+	}
+}
+
+// fillProvenance records into 'provenance' the sources of the values of the environment
+// items of 'config', given the sources recorded by the lookuper used to load them and
+// the names of those whose values were set before loading
+func fillProvenance[T any](provenance Provenance, config *T, lookedUp Provenance, initial map[string]bool) error {
+	envItems, getterErr := GetConfigEnvItems(*config)
+	if getterErr != nil {
+		return getterErr
+	}
+
+	for _, envItem := range envItems {
+		source, found := lookedUp[envItem.Name]
+		switch {
+		case found:
+		case initial[envItem.Name]:
+			source = ValueSource{Kind: SourceInitial}
+		case envItem.Default != "":
+			source = ValueSource{Kind: SourceDefault}
+		default:
+			source = ValueSource{Kind: SourceNone}
+		}
+		provenance[envItem.Name] = source
+	}
+	return nil
+}
+
+// getInitializedNames returns the names of the environment items of 'config' having non-zero values
+func getInitializedNames[T any](config T) (map[string]bool, error) {
+	envItems, getterErr := GetConfigEnvItems(config)
+	if getterErr != nil {
+		return nil, getterErr
+	}
+	initialized := make(map[string]bool)
+	for _, envItem := range envItems {
+		if envItem.Val != nil && !reflect.ValueOf(envItem.Val).IsZero() {
+			initialized[envItem.Name] = true
+		}
+	}
+	return initialized, nil
+}
+
+// flagsLookuper returns an envconfig.Lookuper for the values of the flags set in 'flagSet',
+// along with their sources; flags are matched to environment names ignoring case and
+// treating '-' as '_' (e.g., "-conversion-rate" sets "CONVERSION_RATE")
+func flagsLookuper(flagSet *flag.FlagSet) sourcedLookuper {
+	flagValues := make(map[string]string)
+	flagNames := make(map[string]string)
+	flagSet.Visit(func(f *flag.Flag) {
+		envName := strings.ToUpper(strings.ReplaceAll(f.Name, "-", "_"))
+		flagValues[envName] = f.Value.String()
+		flagNames[envName] = f.Name
+	})
+	return sourcedLookuper{
+		lookuper: envconfig.MapLookuper(flagValues),
+		sourceOf: func(envName string) ValueSource {
+			return ValueSource{Kind: SourceFlag, Flag: flagNames[envName]}
+		},
+	}
+}
+
+// findEntryLines returns the (1-based) numbers of the lines within 'contents' (written in 'format')
+// holding the configuration entries found there; when an entry appears more than once, the last
+// wins.  The lines continuing quoted dotenv values spanning several lines are skipped.
+func findEntryLines(contents []byte, format Format) map[string]int {
+	entryLines := make(map[string]int)
+	scanEntries(contents, format, func(envName string, lineNumber int, _ string) {
+		entryLines[envName] = lineNumber
+	})
+	return entryLines
+}
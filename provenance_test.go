@@ -0,0 +1,98 @@
+package configurator
+
+import (
+	"flag"
+	"os"
+	"path/filepath"
+	"testing"
+
+	"github.com/stretchr/testify/require"
+)
+
+func TestApiLoadProvenance(t *testing.T) {
+
+	type testConfig struct {
+		S1 string  `env:"PROV_S1"`
+		S2 string  `env:"PROV_S2,default=dflt"`
+		S3 string  `env:"PROV_S3"`
+		F4 float64 `env:"PROV_F4"`
+		S5 string  `env:"PROV_S5"`
+		S6 string  `env:"PROV_S6" secret:"hide"`
+		S7 string  `env:"PROV_S7"`
+		S8 string  `env:"PROV_S8"`
+	}
+
+	requirer := require.New(t)
+	unsetTestEnv(t, "PROV_S1", "PROV_S2", "PROV_S3", "PROV_F4", "PROV_S6", "PROV_S7", "PROV_S8")
+	t.Setenv("PROV_S3", "exported")
+
+	tempDir := t.TempDir()
+	envFileName := filepath.Join(tempDir, "config.env")
+	requirer.NoError(os.WriteFile(envFileName, []byte("# comment\nPROV_S1=from file\n\nexport PROV_S3=shadowed\nPROV_F4=1.5\n"), 0600))
+	store := &FileSecretStore{FileName: filepath.Join(tempDir, "secrets.env")}
+	requirer.NoError(store.SaveSecrets(map[string]string{"PROV_S6": "secret"}))
+
+	flagSet := flag.NewFlagSet("test", flag.ContinueOnError)
+	flagSet.String("prov-f4", "", "")
+	flagSet.String("prov-s8", "unset", "")
+	requirer.NoError(flagSet.Parse([]string{"-prov-f4=2.5"}))
+
+	provenance := Provenance{}
+	config := testConfig{S7: "initial"}
+	requirer.NoError(LoadConfig(envFileName, &config, WithSecretStore(store), WithFlags(flagSet), WithProvenance(provenance)))
+	requirer.Equal(testConfig{S1: "from file", S2: "dflt", S3: "exported", F4: 2.5, S6: "secret", S7: "initial"}, config)
+	requirer.Equal(Provenance{
+		"PROV_S1": {Kind: SourceFile, File: envFileName, Line: 2},
+		"PROV_S2": {Kind: SourceDefault},
+		"PROV_S3": {Kind: SourceEnv, Shadowed: &ValueSource{Kind: SourceFile, File: envFileName, Line: 4}},
+		"PROV_F4": {Kind: SourceFlag, Flag: "prov-f4"},
+		"PROV_S5": {Kind: SourceNone},
+		"PROV_S6": {Kind: SourceSecretStore},
+		"PROV_S7": {Kind: SourceInitial},
+		"PROV_S8": {Kind: SourceNone},
+	}, provenance)
+
+	requirer.Equal("file "+envFileName+":2", provenance["PROV_S1"].String())
+	requirer.Equal("flag -prov-f4", provenance["PROV_F4"].String())
+	requirer.Equal("env (shadowing file "+envFileName+":4)", provenance["PROV_S3"].String())
+}
+
+func TestFindEntryLines(t *testing.T) {
+
+	testCases := []struct {
+		name          string
+		contents      string
+		format        Format
+		expectedLines map[string]int
+	}{
+		{
+			name:          "formats",
+			contents:      "# A=commented\nB: yaml\n  A = toml\n  \"A\": \"json\"\nexport C=dotenv\nD=\n",
+			format:        DotenvFormat,
+			expectedLines: map[string]int{"A": 4, "B": 2, "C": 5, "D": 6},
+		},
+		{
+			name:          "multi-line values",
+			contents:      "A=\"first\nB=inside\n\"\nB=outside\nC='x\nexport A=inside'\nD=\"escaped\\\"\nE=inside\"\nF=`one line`\n",
+			format:        DotenvFormat,
+			expectedLines: map[string]int{"A": 1, "B": 4, "C": 5, "D": 7, "F": 9},
+		},
+		{
+			name:          "export",
+			contents:      "export A=\"x\nexport B=inside\"\nexport\tB=1\n  export C='y'\nexport=2\n",
+			format:        DotenvFormat,
+			expectedLines: map[string]int{"A": 1, "B": 3, "C": 4, "export": 5},
+		},
+		{
+			name:          "not dotenv",
+			contents:      "A: \"x\nB: 2\n",
+			format:        YAMLFormat,
+			expectedLines: map[string]int{"A": 1, "B": 2},
+		},
+	}
+	for _, testCase := range testCases {
+		t.Run(testCase.name, func(t *testing.T) {
+			require.Equal(t, testCase.expectedLines, findEntryLines([]byte(testCase.contents), testCase.format))
+		})
+	}
+}
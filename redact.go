@@ -0,0 +1,153 @@
+package configurator
+
+import (
+	"fmt"
+	"log/slog"
+	"reflect"
+	"strings"
+	"unicode/utf8"
+)
+
+// Values of the `secret` tag having special meaning; any other non-empty
+// value marks the item as secret and is treated the same as SecretHide.
+const (
+	SecretMask = "mask"
+	SecretHide = "hide"
+)
+
+// suppressedValue is displayed in place of the value of a hidden secret
+const suppressedValue = "<suppressed>"
+
+// IsSecret reports whether the item was tagged as holding a secret value
+func (item ConfigEnvItem) IsSecret() bool {
+	return item.Secret != ""
+}
+
+// DisplayVal returns the value of the item as it should be shown to a user or
+// written to a log: items tagged `secret:"mask"` are replaced by a string of '*'
+// of the same length, other secrets are replaced by "<suppressed>", and the value
+// of non-secret items is returned unchanged.
+func (item ConfigEnvItem) DisplayVal() any {
+	switch item.Secret {
+	case "":
+		return item.Val
+	case SecretMask:
+		return strings.Repeat("*", utf8.RuneCountInString(fmt.Sprintf("%v", item.Val)))
+	default:
+		return suppressedValue
+	}
+}
+
+// String renders the item as "NAME=value", using its redacted display value
+func (item ConfigEnvItem) String() string {
+	return fmt.Sprintf("%s=%v", item.Name, item.DisplayVal())
+}
+
+// Format renders the item as String does for the "%v" and "%s" verbs; other verbs (and "%#v")
+// format the fields of the item as usual, but with its redacted display value (see DisplayVal)
+func (item ConfigEnvItem) Format(f fmt.State, verb rune) {
+	if (verb == 'v' && !f.Flag('#')) || verb == 's' {
+		_, _ = fmt.Fprint(f, item.String())
+		return
+	}
+	redactedItem := item
+	redactedItem.Val = item.DisplayVal()
+	type plainConfigEnvItem ConfigEnvItem // drops the methods, avoiding recursion
+	_, _ = fmt.Fprintf(f, fmt.FormatString(f, verb), plainConfigEnvItem(redactedItem))
+}
+
+// LogValue implements slog.LogValuer, logging the redacted display value of the item
+func (item ConfigEnvItem) LogValue() slog.Value {
+	return slog.AnyValue(item.DisplayVal())
+}
+
+// RedactConfig returns a copy of 'config' in which the values of all items tagged
+// as secret are replaced: string values by their display value (see DisplayVal),
+// values of other kinds by their zero value.
+func RedactConfig[T any](config T) (T, error) {
+	redactedConfig := config
+	cfgStructType, cfgStructElements, getConfigInfoErr := getConfigStructInfo(&redactedConfig)
+	if getConfigInfoErr != nil {
+		return config, getConfigInfoErr
+	}
+
+	for fieldIndex := 0; fieldIndex < cfgStructType.NumField(); fieldIndex++ {
+
+		cfgStructFieldTag := cfgStructType.Field(fieldIndex).Tag
+		secretTagVal, okS := cfgStructFieldTag.Lookup("secret")
+		if !okS || secretTagVal == "" {
+			continue
+		}
+
+		cfgStructFieldElement := cfgStructElements.Field(fieldIndex)
+		if !cfgStructFieldElement.CanInterface() {
+			// e.g., private visibility
+			continue
+		}
+		if !cfgStructFieldElement.CanSet() {
+			return config, fmt.Errorf("can't redact(%s); not settable", cfgStructType.Field(fieldIndex).Name)
+		}
+
+		if cfgStructFieldElement.Kind() == reflect.String {
+			item := ConfigEnvItem{Val: cfgStructFieldElement.String(), Secret: secretTagVal}
+			cfgStructFieldElement.SetString(item.DisplayVal().(string))
+			continue
+		}
+		cfgStructFieldElement.Set(reflect.Zero(cfgStructFieldElement.Type()))
+	}
+
+	return redactedConfig, nil
+}
+
+// RedactedConfig wraps a configuration structure so that it can be safely
+// formatted (using 'fmt') or logged (using 'slog') without revealing secrets
+type RedactedConfig[T any] struct {
+	config T
+}
+
+// Redacted wraps 'config' so that printing or logging it never reveals its secret values
+func Redacted[T any](config T) RedactedConfig[T] {
+	return RedactedConfig[T]{config: config}
+}
+
+// String renders the environment items of the configuration as "{NAME=value ...}"
+func (rc RedactedConfig[T]) String() string {
+	items, getterErr := GetConfigEnvItems(rc.config)
+	if getterErr != nil {
+		return fmt.Sprintf("%%!(REDACTED=%v)", getterErr)
+	}
+	itemStrings := make([]string, 0, len(items))
+	for _, item := range items {
+		itemStrings = append(itemStrings, item.String())
+	}
+	return "{" + strings.Join(itemStrings, " ") + "}"
+}
+
+// Format ensures secret values of the configuration aren't revealed by any 'fmt' verb;
+// "%#v" renders the Go syntax representation of a redacted copy of the configuration.
+func (rc RedactedConfig[T]) Format(f fmt.State, verb rune) {
+	if verb == 'v' && f.Flag('#') {
+		redactedConfig, redactErr := RedactConfig(rc.config)
+		if redactErr != nil {
+			_, _ = fmt.Fprintf(f, "%%!(REDACTED=%v)", redactErr)
+			return
+		}
+		_, _ = fmt.Fprintf(f, "%#v", redactedConfig)
+		return
+	}
+	_, _ = fmt.Fprint(f, rc.String())
+}
+
+// LogValue implements slog.LogValuer, logging the configuration as a group
+// of its environment items, each having its redacted display value
+func (rc RedactedConfig[T]) LogValue() slog.Value {
+	items, getterErr := GetConfigEnvItems(rc.config)
+	if getterErr != nil {
+		return slog.StringValue(fmt.Sprintf("%%!(REDACTED=%v)", getterErr))
+	}
+	attrs := make([]slog.Attr, 0, len(items))
+	for _, item := range items {
+		attrs = append(attrs, slog.Any(item.Name, item))
+	}
+	return slog.GroupValue(attrs...)
+}
@@ -0,0 +1,103 @@
+package configurator
+
+import (
+	"bytes"
+	"fmt"
+	"log/slog"
+	"testing"
+
+	"github.com/stretchr/testify/require"
+)
+
+func TestDisplayVal(t *testing.T) {
+
+	testCases := []struct {
+		name           string
+		item           ConfigEnvItem
+		expected       any
+		expectedString string
+	}{
+		{
+			name:           "not secret",
+			item:           ConfigEnvItem{Name: "N", Val: 42},
+			expected:       42,
+			expectedString: "N=42",
+		},
+		{
+			name:           "masked",
+			item:           ConfigEnvItem{Name: "N", Val: "3454", Secret: SecretMask},
+			expected:       "****",
+			expectedString: "N=****",
+		},
+		{
+			name:           "hidden",
+			item:           ConfigEnvItem{Name: "N", Val: "forYourEyesOnly", Secret: SecretHide},
+			expected:       "<suppressed>",
+			expectedString: "N=<suppressed>",
+		},
+		{
+			name:           "other secret tag value",
+			item:           ConfigEnvItem{Name: "N", Val: "forYourEyesOnly", Secret: "true"},
+			expected:       "<suppressed>",
+			expectedString: "N=<suppressed>",
+		},
+	}
+
+	for _, tc := range testCases {
+		t.Run(tc.name, func(t *testing.T) {
+			requirer := require.New(t)
+			requirer.Equal(tc.expected, tc.item.DisplayVal())
+			requirer.Equal(tc.expectedString, tc.item.String())
+			requirer.Equal(tc.expectedString, fmt.Sprintf("%v", tc.item))
+			requirer.Equal(tc.expectedString, fmt.Sprintf("%s", tc.item))
+			if !tc.item.IsSecret() {
+				return
+			}
+			for _, verb := range []string{"%+v", "%#v", "%q", "%d"} {
+				formatted := fmt.Sprintf(verb, tc.item)
+				requirer.NotContains(formatted, tc.item.Val, verb)
+				requirer.Contains(formatted, fmt.Sprintf(verb, tc.expected), verb)
+			}
+		})
+	}
+}
+
+func TestRedaction(t *testing.T) {
+
+	type testConfig struct {
+		S1 string  `env:"V_S1"`
+		S2 string  `env:"V_S2" secret:"mask"`
+		S3 string  `env:"V_S3" secret:"hide"`
+		F4 float64 `env:"V_F4" secret:"true"`
+		S5 string
+	}
+
+	const secretValue = "forYourEyesOnly"
+	config := testConfig{S1: "visible", S2: "3454", S3: secretValue, F4: 2.71828, S5: "untagged"}
+
+	requirer := require.New(t)
+
+	redactedConfig, redactErr := RedactConfig(config)
+	requirer.NoError(redactErr)
+	requirer.Equal(testConfig{S1: "visible", S2: "****", S3: "<suppressed>", S5: "untagged"}, redactedConfig)
+	requirer.Equal(secretValue, config.S3, "original must not be modified")
+
+	items, getterErr := GetConfigEnvItems(config)
+	requirer.NoError(getterErr)
+	requirer.Equal("V_S2=****", items[1].String())
+	requirer.NotContains(fmt.Sprintf("%#v", items[2]), secretValue)
+	requirer.NotContains(fmt.Sprintf("%v", items), secretValue)
+
+	redacted := Redacted(config)
+	requirer.Equal("{V_S1=visible V_S2=**** V_S3=<suppressed> V_F4=<suppressed>}", fmt.Sprintf("%v", redacted))
+	for _, verb := range []string{"%v", "%+v", "%#v", "%s", "%q", "%x"} {
+		requirer.NotContains(fmt.Sprintf(verb, redacted), secretValue, verb)
+		requirer.NotContains(fmt.Sprintf(verb, redacted), "2.71828", verb)
+	}
+
+	logBuffer := &bytes.Buffer{}
+	slog.New(slog.NewTextHandler(logBuffer, nil)).Info("loaded", "config", redacted)
+	requirer.Contains(logBuffer.String(), "config.V_S1=visible")
+	requirer.Contains(logBuffer.String(), "config.V_S3=<suppressed>")
+	requirer.NotContains(logBuffer.String(), secretValue)
+}
@@ -3,37 +3,194 @@ package configurator
 import (
 	"fmt"
 	"io"
-	"log"
 	"os"
-	"sort"
 )
 
 // SaveConfig saves the current 'config' values into 'configFile', and
-// updates the values of the corresponding environment variables.
-func SaveConfig[T any](configFileName string, config T) error {
+// updates the values of the corresponding environment variables.  When a
+// SecretStore is given (see WithSecretStore), the values of items tagged as
+// `secret` are saved into it instead, and removed from both the file and
+// the environment.  Otherwise, when an encryption key is given (see
+// WithEncryptionKey), their values are saved as encrypted envelopes.  Values
+// held in value files (see WithFileIndirection and WithValuesDir), typically
+// read-only, aren't saved, leaving the indirections to them intact; a warning
+// is logged for those which were changed.  Values obtained
+// from credential helper commands (see the `helper` tag and WithCredentialHelpers)
+// aren't saved, with the entries naming the commands kept.  When a profile
+// is selected (see WithProfile), only the values differing from those in
+// 'configFile' are saved, into the configuration file of the profile.  Items
+// are always saved using their names, rather than deprecated aliases (see
+// the `aliases` tag).  Changed values of entries found in files included by
+// the configuration file are saved into those files.
+func SaveConfig[T any](configFileName string, config T, opts ...Option) error {
+	options := newOptions(opts)
 	envItems, getterErr := GetConfigEnvItems(config)
 	if getterErr != nil {
 		return getterErr
 	}
-	configMap := make(map[string]any, len(envItems))
+	saved := getSavedEntries(configFileName, envItems, options)
+	for _, valueFile := range sortedKeys(saved.valueFiles) {
+		if currentVal, readErr := readValueFile(valueFile); readErr != nil || currentVal != saved.valueFiles[valueFile] {
+			options.logger.Warn("changed value held in a value file isn't saved", "file", valueFile)
+		}
+	}
+	configMap := saved.configMap
+	for _, envName := range saved.encrypted {
+		encrypted, encryptErr := options.encryptValue(envName, fmt.Sprintf("%v", configMap[envName]))
+		if encryptErr != nil {
+			return fmt.Errorf("can't encrypt(%s): %w", envName, encryptErr)
+		}
+		configMap[envName] = encrypted
+	}
+	if len(saved.secrets) != 0 {
+		if updateErr := updateSecrets(options.secretStore, saved.secrets); updateErr != nil {
+			return updateErr
+		}
+	}
+	baseFileName := configFileName
+	if profile := options.profileName(); profile != "" {
+		configMap = profileOverrides(configFileName, configMap, options)
+		configFileName = ProfileFile(configFileName, profile)
+	}
+	configMap, includedMaps := splitIncludedEntries(configFileName, configMap, options)
+	for _, includedFile := range sortedKeys(includedMaps) {
+		// the version of the configuration (see WithMigrations) is recorded only in the including file
+		if saveErr := saveConfigMap(includedFile, includedMaps[includedFile], options); saveErr != nil {
+			return saveErr
+		}
+	}
+	if configFileName != baseFileName {
+		// the version of the configuration (see WithMigrations) is recorded only in the base file
+		if saveErr := saveConfigMap(configFileName, configMap, options); saveErr != nil {
+			return saveErr
+		}
+		if recordErr := options.recordVersion(baseFileName); recordErr != nil {
+			return recordErr
+		}
+	} else if saveErr := SaveConfigMap(configFileName, configMap, opts...); saveErr != nil {
+		return saveErr
+	}
 	for _, envItem := range envItems {
-		configMap[envItem.Name] = envItem.Val
+		for _, alias := range envItem.Aliases {
+			// values loaded from deprecated names replaced in the file no longer apply
+			if _, isExternal := lookupExternalEnv(alias); !isExternal {
+				if unsetErr := unsetManagedEnv(alias); unsetErr != nil {
+					return unsetErr
+				}
+			}
+		}
 	}
-	return SaveConfigMap(configFileName, configMap)
+	return nil
+}
+
+// savedEntries describes what SaveConfig saves for the items of a configuration structure
+type savedEntries struct {
+	// configMap holds the entries to be saved into the configuration file, those having
+	// nil values to be removed from it; the values of those named by 'encrypted' are yet
+	// to be encrypted
+	configMap  map[string]any
+	encrypted  []string
+	secrets    map[string]string // values to be saved into the secret store
+	valueFiles map[string]string // values held in value files, left unsaved, by file name
 }
 
-// SaveConfigMap saves the map of environment name: environment value entries into 'configFile'.
-func SaveConfigMap(configFileName string, configMap map[string]any) error {
+// getSavedEntries returns what SaveConfig saves for 'envItems' given 'configFile'
+func getSavedEntries(configFile string, envItems []ConfigEnvItem, o *options) *savedEntries {
+	// the templates of unchanged values (see interpolate) are saved rather than their expansions
+	fileEnv, _, _ := readConfigLayers(configFile, o)
+	expandedEnv, _ := o.interpolate(fileEnv)
+	saved := &savedEntries{
+		configMap:  make(map[string]any, len(envItems)),
+		secrets:    make(map[string]string),
+		valueFiles: make(map[string]string),
+	}
+	for _, envItem := range envItems {
+		// values obtained from credential helpers are never saved
+		if _, isHelper := o.helperCommandOf(fileEnv[envItem.Name]); isHelper {
+			saved.configMap[envItem.Name] = fileEnv[envItem.Name]
+			continue
+		}
+		_, inFile := fileEnv[envItem.Name]
+		if envItem.Helper != "" && !inFile {
+			continue
+		}
+		// values found in the configuration file take precedence over those in value files
+		if valueFile, found := o.valueFileFor(envItem.Name); found && !inFile {
+			saved.valueFiles[valueFile] = fmt.Sprintf("%v", envItem.Val)
+			saved.configMap[envItem.Name] = nil
+			indirectionName := envItem.Name + FileIndirectionSuffix
+			if _, isExternal := lookupExternalEnv(indirectionName); !isExternal && o.fileIndirection && os.Getenv(indirectionName) == valueFile {
+				// the indirection was loaded from the configuration file
+				saved.configMap[indirectionName] = valueFile
+			}
+			continue
+		}
+		switch {
+		case envItem.IsSecret() && o.secretStore != nil:
+			saved.secrets[envItem.Name] = fmt.Sprintf("%v", envItem.Val)
+			saved.configMap[envItem.Name] = nil
+		case envItem.IsSecret() && o.encryptionKey != nil:
+			saved.configMap[envItem.Name] = fmt.Sprintf("%v", envItem.Val)
+			saved.encrypted = append(saved.encrypted, envItem.Name)
+		default:
+			saved.configMap[envItem.Name] = o.templateFor(envItem.Name, envItem.Val, fileEnv, expandedEnv)
+		}
+	}
+	return saved
+}
+
+// SaveConfigMap saves the map of environment name: environment value entries into 'configFile',
+// in the format indicated by its extension (see FormatForFile) unless given (see WithFormat).
+// Given WithBackups, the previous contents of the file are kept as a backup.
+func SaveConfigMap(configFileName string, configMap map[string]any, opts ...Option) error {
+	options := newOptions(opts)
+	return saveConfigMap(configFileName, options.withVersion(configMap), options)
+}
+
+// saveConfigMap saves 'configMap' into 'configFileName' as described by SaveConfigMap, without
+// recording the version of the configuration into it
+func saveConfigMap(configFileName string, configMap map[string]any, options *options) error {
+	format := options.formatFor(configFileName)
+	// the entries of included files aren't merged into the file, so its include directives are kept
+	directiveTexts := readIncludeDirectives(configFileName, format)
+	if options.backups > 0 {
+		if backupErr := backupConfigFile(configFileName, options.backups, options); backupErr != nil {
+			return backupErr
+		}
+	}
 	configFile, openErr := os.OpenFile(configFileName, os.O_CREATE|os.O_WRONLY|os.O_TRUNC, 0600)
 	if openErr != nil {
 		return openErr
 	}
 	defer func() {
 		if closeErr := configFile.Close(); closeErr != nil {
-			log.Printf("NOTE: error closing %s: %v\n", configFileName, closeErr)
+			options.logger.Warn("error closing configuration file", "file", configFileName, "error", closeErr)
 		}
 	}()
-	return updateConfigFromMap(configFile, configMap)
+	if writeErr := writeIncludeDirectives(configFile, directiveTexts); writeErr != nil {
+		return writeErr
+	}
+	for _, envName := range getShadowedNames(configMap, options) {
+		options.logger.Warn("saved value is shadowed by the environment", "file", configFileName, "env", envName)
+	}
+	return updateConfigFromMap(configFile, configMap, format, options)
+}
+
+// getShadowedNames returns the names of the entries of 'configMap' whose values differ from
+// those set into the environment other than by configurator (e.g., exported by the user's
+// shell), which would therefore take precedence over them when next loaded
+func getShadowedNames(configMap map[string]any, o *options) []string {
+	expandedEnv, interpolateErr := o.expandConfigMap(configMap)
+	if interpolateErr != nil {
+		return nil
+	}
+	var shadowedNames []string
+	for _, envName := range sortedKeys(expandedEnv) {
+		if externalVal, isExternal := lookupExternalEnv(envName); isExternal && externalVal != expandedEnv[envName] {
+			shadowedNames = append(shadowedNames, envName)
+		}
+	}
+	return shadowedNames
 }
 
 // updateConfigFromMap updates both the written configuration and the environment
@@ -41,42 +198,32 @@ func SaveConfigMap(configFileName string, configMap map[string]any) error {
 // Configuration entries with nil values will be removed from both targets.  NOTE:
 // no transactional guarantees are provided; if an error is returned, partial
 // update(s) may have been made.
-func updateConfigFromMap(truncatedConfigFile io.Writer, fullConfigMap map[string]any) error {
-	sortedEnvVarNames := make([]string, len(fullConfigMap))
-	for envVarName := range fullConfigMap {
-		sortedEnvVarNames = append(sortedEnvVarNames, envVarName)
-	}
-	sort.Strings(sortedEnvVarNames)
-
+func updateConfigFromMap(truncatedConfigFile io.Writer, fullConfigMap map[string]any, format Format, o *options) error {
 	// write the new configuration entries
-	for _, envVarName := range sortedEnvVarNames {
-		envVal := fullConfigMap[envVarName]
-		if envVal == nil {
-			continue
-		}
-		if _, printErr := fmt.Fprintf(truncatedConfigFile, "%s=%v\n", envVarName, envVal); printErr != nil {
-			return printErr
-		}
+	if writeErr := format.Write(truncatedConfigFile, fullConfigMap); writeErr != nil {
+		return writeErr
 	}
-	// update the environment
-	cantUpdateVars := make(map[string][]string)
+	// update the environment, with references to variables expanded
+	expandedEnv, interpolateErr := o.expandConfigMap(fullConfigMap)
+	if interpolateErr != nil {
+		return interpolateErr
+	}
+	sortedEnvVarNames := sortedKeys(fullConfigMap)
+	var cantUpdateErrs []error
 	for _, envVarName := range sortedEnvVarNames {
 		envVal := fullConfigMap[envVarName]
 		if envVal == nil {
 			_, found := os.LookupEnv(envVarName)
 			if found {
-				if unSetEnvErr := os.Unsetenv(envVarName); unSetEnvErr != nil {
-					cantUpdateVars[unSetEnvErr.Error()] = append(cantUpdateVars["unset "+unSetEnvErr.Error()], envVarName)
+				if unSetEnvErr := unsetManagedEnv(envVarName); unSetEnvErr != nil {
+					cantUpdateErrs = append(cantUpdateErrs, &FieldError{EnvName: envVarName, Err: fmt.Errorf("can't unset: %w", unSetEnvErr)})
 				}
 			}
 			continue
 		}
-		if setEnvErr := os.Setenv(envVarName, fmt.Sprintf("%v", envVal)); setEnvErr != nil {
-			cantUpdateVars[setEnvErr.Error()] = append(cantUpdateVars["set "+setEnvErr.Error()], envVarName)
+		if setEnvErr := setManagedEnv(envVarName, expandedEnv[envVarName]); setEnvErr != nil {
+			cantUpdateErrs = append(cantUpdateErrs, &FieldError{EnvName: envVarName, Err: fmt.Errorf("can't set: %w", setEnvErr)})
 		}
 	}
-	if len(cantUpdateVars) != 0 {
-		return fmt.Errorf("couldn't update environment variable(s): %s", cantUpdateVars)
-	}
-	return nil
+	return newMultiError(cantUpdateErrs)
 }
@@ -75,7 +75,7 @@ func TestUpdateConfigFromMap(t *testing.T) {
 
 			// invoke the writer
 			writer := &bytes.Buffer{}
-			requirer.NoError(updateConfigFromMap(writer, tc.configVars))
+			requirer.NoError(updateConfigFromMap(writer, tc.configVars, DotenvFormat, newOptions(nil)))
 			requirer.Equal(tc.expectedOutput, writer.String())
 
 			// ensure the environment now has the correct values
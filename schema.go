@@ -0,0 +1,335 @@
+package configurator
+
+import (
+	"bytes"
+	"encoding"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"os"
+	"reflect"
+	"slices"
+	"sort"
+	"strconv"
+	"strings"
+	"time"
+
+	"gopkg.in/yaml.v3"
+)
+
+// SchemaVersion identifies the version of JSON Schema generated by ConfigSchema
+const SchemaVersion = "https://json-schema.org/draft/2020-12/schema"
+
+// Schema is a JSON Schema document describing the entries of a configuration file
+type Schema struct {
+	Schema     string                     `json:"$schema,omitempty" yaml:"$schema,omitempty"`
+	Title      string                     `json:"title,omitempty" yaml:"title,omitempty"`
+	Type       string                     `json:"type" yaml:"type"`
+	Properties map[string]*SchemaProperty `json:"properties" yaml:"properties"`
+	Required   []string                   `json:"required,omitempty" yaml:"required,omitempty"`
+}
+
+// SchemaProperty is the JSON Schema of a single configuration entry.  Secret entries
+// are marked "writeOnly", with their `secret` tag value given as "x-secret".
+type SchemaProperty struct {
+	Type        string          `json:"type" yaml:"type"`
+	Description string          `json:"description,omitempty" yaml:"description,omitempty"`
+	Default     any             `json:"default,omitempty" yaml:"default,omitempty"`
+	Enum        []any           `json:"enum,omitempty" yaml:"enum,omitempty"`
+	Minimum     *float64        `json:"minimum,omitempty" yaml:"minimum,omitempty"`
+	Items       *SchemaProperty `json:"items,omitempty" yaml:"items,omitempty"`
+	WriteOnly   bool            `json:"writeOnly,omitempty" yaml:"writeOnly,omitempty"`
+	Secret      string          `json:"x-secret,omitempty" yaml:"x-secret,omitempty"`
+}
+
+var (
+	durationType        = reflect.TypeOf(time.Duration(0))
+	textUnmarshalerType = reflect.TypeOf((*encoding.TextUnmarshaler)(nil)).Elem()
+)
+
+// ConfigSchema returns the JSON Schema describing the environment items of 'config', using
+// their kinds, defaults, "required" options, descriptions (`desc` tag), allowed values
+// (`enum` tag) and `secret` tags
+func ConfigSchema[T any](config T) (*Schema, error) {
+	envItems, getterErr := GetConfigEnvItems(config)
+	if getterErr != nil {
+		return nil, getterErr
+	}
+	schema := &Schema{
+		Schema:     SchemaVersion,
+		Title:      reflect.TypeOf(config).Name(),
+		Type:       "object",
+		Properties: make(map[string]*SchemaProperty, len(envItems)),
+	}
+	for _, envItem := range envItems {
+		property := schemaPropertyForType(reflect.TypeOf(envItem.Val))
+		property.Description = envItem.Description
+		if envItem.Default != "" {
+			property.Default = schemaValue(property, envItem.Default)
+		}
+		enumProperty := property
+		if property.Items != nil {
+			// the allowed values of a list are those of its items
+			enumProperty = property.Items
+		}
+		for _, option := range envItem.Options {
+			enumProperty.Enum = append(enumProperty.Enum, schemaValue(enumProperty, option))
+		}
+		if envItem.IsSecret() {
+			property.WriteOnly = true
+			property.Secret = envItem.Secret
+		}
+		schema.Properties[envItem.Name] = property
+		if envItem.Required {
+			schema.Required = append(schema.Required, envItem.Name)
+		}
+	}
+	sort.Strings(schema.Required)
+	return schema, nil
+}
+
+// WriteConfigSchema writes the JSON Schema describing the environment items of 'config' to 'w'
+func WriteConfigSchema[T any](w io.Writer, config T) error {
+	schema, schemaErr := ConfigSchema(config)
+	if schemaErr != nil {
+		return schemaErr
+	}
+	encoder := json.NewEncoder(w)
+	encoder.SetIndent("", "  ")
+	return encoder.Encode(schema)
+}
+
+// ReadSchema reads a Schema (e.g., as written by WriteConfigSchema) from 'r'.  Since JSON is
+// a subset of YAML, the schema can also be given as a (more easily written) YAML manifest.
+func ReadSchema(r io.Reader) (*Schema, error) {
+	schema := &Schema{}
+	if decodeErr := yaml.NewDecoder(r).Decode(schema); decodeErr != nil && !errors.Is(decodeErr, io.EOF) {
+		return nil, fmt.Errorf("can't read schema: %w", decodeErr)
+	}
+	for name, property := range schema.Properties {
+		if property == nil || !slices.Contains(schemaTypes, property.Type) {
+			return nil, fmt.Errorf("unsupported schema type for(%s)", name)
+		}
+		if property.Items != nil && (property.Type != "array" || !slices.Contains(schemaTypes[:len(schemaTypes)-1], property.Items.Type)) {
+			// comma separated lists can't hold lists
+			return nil, fmt.Errorf("unsupported schema items type for(%s)", name)
+		}
+	}
+	return schema, nil
+}
+
+// ReadSchemaFile reads a Schema from 'schemaFileName' (see ReadSchema)
+func ReadSchemaFile(schemaFileName string) (*Schema, error) {
+	contents, readErr := os.ReadFile(schemaFileName)
+	if readErr != nil {
+		return nil, readErr
+	}
+	return ReadSchema(bytes.NewReader(contents))
+}
+
+// ConfigEnvItems returns the items described by the schema, in order of their names, with
+// the values found in 'configMap' (as read by ReadConfigMap) or else their default values
+func (s *Schema) ConfigEnvItems(configMap map[string]any) []ConfigEnvItem {
+	envItems := make([]ConfigEnvItem, 0, len(s.Properties))
+	for _, envName := range sortedKeys(s.Properties) {
+		property := s.Properties[envName]
+		envItem := ConfigEnvItem{
+			Name:        envName,
+			Kind:        property.kind(),
+			Required:    slices.Contains(s.Required, envName),
+			Description: property.Description,
+			Secret:      property.Secret,
+		}
+		if property.WriteOnly && envItem.Secret == "" {
+			envItem.Secret = SecretMask
+		}
+		if property.Default != nil {
+			envItem.Default = schemaValueString(property.Default)
+		}
+		enumProperty := property
+		if property.Items != nil {
+			enumProperty = property.Items
+		}
+		for _, option := range enumProperty.Enum {
+			envItem.Options = append(envItem.Options, schemaValueString(option))
+		}
+		valueAsString := envItem.Default
+		if envVal := configMap[envName]; envVal != nil {
+			valueAsString = schemaValueString(envVal)
+		}
+		envItem.Val = property.configValue(valueAsString)
+		envItems = append(envItems, envItem)
+	}
+	return envItems
+}
+
+// Validate returns a MultiError holding a FieldError for each of the entries of 'configMap'
+// (as read by ReadConfigMap) which doesn't conform to the schema, or nil if all do
+func (s *Schema) Validate(configMap map[string]any) error {
+	var validateErrs []error
+	for _, envName := range s.Required {
+		if configMap[envName] == nil {
+			validateErrs = append(validateErrs, &FieldError{EnvName: envName, Err: ErrMissingRequired})
+		}
+	}
+	for _, envName := range sortedKeys(s.Properties) {
+		if envVal := configMap[envName]; envVal != nil {
+			if validateErr := s.ValidateValue(envName, schemaValueString(envVal)); validateErr != nil {
+				validateErrs = append(validateErrs, validateErr)
+			}
+		}
+	}
+	return newMultiError(validateErrs)
+}
+
+// ValidateValue returns a FieldError if 'valueAsString' isn't a valid value of the entry
+// named 'envName', wrapping ErrNotFound if the schema doesn't describe the entry
+func (s *Schema) ValidateValue(envName, valueAsString string) error {
+	property, found := s.Properties[envName]
+	if !found {
+		return &FieldError{EnvName: envName, Err: ErrNotFound}
+	}
+	if validateErr := property.validate(valueAsString); validateErr != nil {
+		secret := property.Secret
+		if property.WriteOnly && secret == "" {
+			secret = SecretMask
+		}
+		return newFieldError(envName, valueAsString, secret, validateErr)
+	}
+	return nil
+}
+
+// schemaTypes are the types of properties supported by Schema; all but "array" are
+// supported as the types of its items
+var schemaTypes = []string{"string", "boolean", "integer", "number", "array"}
+
+// kind returns the kind of the values described by the property
+func (p *SchemaProperty) kind() reflect.Kind {
+	switch p.Type {
+	case "boolean":
+		return reflect.Bool
+	case "integer":
+		return reflect.Int64
+	case "number":
+		return reflect.Float64
+	case "array":
+		return reflect.Slice
+	}
+	return reflect.String
+}
+
+// items returns the property describing the items of an array property; items are
+// untyped strings unless described
+func (p *SchemaProperty) items() *SchemaProperty {
+	if p.Items == nil {
+		return &SchemaProperty{Type: "string"}
+	}
+	return p.Items
+}
+
+// configValue returns 'valueAsString' as the value saved by SaveConfigMap for the property:
+// a scalar of its type, if it can be converted, otherwise unchanged (e.g., lists remain
+// comma separated)
+func (p *SchemaProperty) configValue(valueAsString string) any {
+	if p.Type == "array" {
+		return valueAsString
+	}
+	return schemaValue(p, valueAsString)
+}
+
+// validate returns an error if 'valueAsString' isn't a valid value of the property
+func (p *SchemaProperty) validate(valueAsString string) error {
+	if p.Type == "array" {
+		if valueAsString == "" {
+			return nil
+		}
+		for itemIndex, item := range strings.Split(valueAsString, ",") {
+			if validateErr := p.items().validate(item); validateErr != nil {
+				return fmt.Errorf("item %d %w", itemIndex+1, validateErr)
+			}
+		}
+		return nil
+	}
+	typedVal := schemaValue(p, valueAsString)
+	if _, isString := typedVal.(string); isString && p.Type != "string" {
+		return fmt.Errorf("isn't of type(%s)", p.Type)
+	}
+	if p.Minimum != nil {
+		if numberVal, _ := strconv.ParseFloat(valueAsString, 64); numberVal < *p.Minimum {
+			return fmt.Errorf("is less than %v", *p.Minimum)
+		}
+	}
+	if len(p.Enum) != 0 && !slices.ContainsFunc(p.Enum, func(option any) bool {
+		return schemaValueString(option) == schemaValueString(typedVal)
+	}) {
+		return fmt.Errorf("isn't one of %v", p.Enum)
+	}
+	return nil
+}
+
+// schemaValueString returns the configuration value of 'schemaVal'; lists are joined by commas
+func schemaValueString(schemaVal any) string {
+	if schemaList, isList := schemaVal.([]any); isList {
+		listEntries := make([]string, 0, len(schemaList))
+		for _, schemaListVal := range schemaList {
+			listEntries = append(listEntries, schemaValueString(schemaListVal))
+		}
+		return strings.Join(listEntries, ",")
+	}
+	return fmt.Sprintf("%v", schemaVal)
+}
+
+// schemaPropertyForType returns the JSON Schema describing values of 'valType'
+func schemaPropertyForType(valType reflect.Type) *SchemaProperty {
+	if valType == nil {
+		// e.g., of an interface field holding nil
+		return &SchemaProperty{Type: "string"}
+	}
+	if valType == durationType || valType == reflect.TypeOf([]byte(nil)) || reflect.PointerTo(valType).Implements(textUnmarshalerType) {
+		// e.g., "1m30s"
+		return &SchemaProperty{Type: "string"}
+	}
+	switch valType.Kind() {
+	case reflect.Bool:
+		return &SchemaProperty{Type: "boolean"}
+	case reflect.Int, reflect.Int8, reflect.Int16, reflect.Int32, reflect.Int64:
+		return &SchemaProperty{Type: "integer"}
+	case reflect.Uint, reflect.Uint8, reflect.Uint16, reflect.Uint32, reflect.Uint64:
+		minimum := float64(0)
+		return &SchemaProperty{Type: "integer", Minimum: &minimum}
+	case reflect.Float32, reflect.Float64:
+		return &SchemaProperty{Type: "number"}
+	case reflect.Slice, reflect.Array:
+		return &SchemaProperty{Type: "array", Items: schemaPropertyForType(valType.Elem())}
+	case reflect.Pointer:
+		return schemaPropertyForType(valType.Elem())
+	}
+	return &SchemaProperty{Type: "string"}
+}
+
+// schemaValue returns 'valueAsString' as a value of the type described by 'property',
+// or unchanged if it can't be converted
+func schemaValue(property *SchemaProperty, valueAsString string) any {
+	switch property.Type {
+	case "boolean":
+		if boolVal, parseErr := strconv.ParseBool(valueAsString); parseErr == nil {
+			return boolVal
+		}
+	case "integer":
+		if intVal, parseErr := strconv.ParseInt(valueAsString, 10, 64); parseErr == nil {
+			return intVal
+		}
+	case "number":
+		if floatVal, parseErr := strconv.ParseFloat(valueAsString, 64); parseErr == nil {
+			return floatVal
+		}
+	case "array":
+		var items []any
+		for _, item := range strings.Split(valueAsString, ",") {
+			items = append(items, schemaValue(property.items(), item))
+		}
+		return items
+	}
+	return valueAsString
+}
@@ -0,0 +1,146 @@
+package configurator
+
+import (
+	"bytes"
+	"encoding/json"
+	"reflect"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/require"
+)
+
+func TestConfigSchema(t *testing.T) {
+
+	type testConfig struct {
+		Host    string        `env:"SCH_HOST,required" desc:"Name of the host"`
+		Port    uint16        `env:"SCH_PORT,default=8080"`
+		Rate    float64       `env:"SCH_RATE,default=2.5"`
+		Verbose bool          `env:"SCH_VERBOSE,default=false"`
+		Level   string        `env:"SCH_LEVEL,default=info" enum:"debug,info,warn"`
+		Timeout time.Duration `env:"SCH_TIMEOUT,default=1m"`
+		Tags    []string      `env:"SCH_TAGS,default=a,b"`
+		Sizes   []int         `env:"SCH_SIZES" enum:"1,2,3"`
+		Key     string        `env:"SCH_KEY,required" secret:"mask"`
+		Ignored string
+	}
+
+	requirer := require.New(t)
+
+	writer := &bytes.Buffer{}
+	requirer.NoError(WriteConfigSchema(writer, testConfig{}))
+
+	var schema map[string]any
+	requirer.NoError(json.Unmarshal(writer.Bytes(), &schema))
+	requirer.Equal(SchemaVersion, schema["$schema"])
+	requirer.Equal("testConfig", schema["title"])
+	requirer.Equal("object", schema["type"])
+	requirer.Equal([]any{"SCH_HOST", "SCH_KEY"}, schema["required"])
+
+	properties := schema["properties"].(map[string]any)
+	requirer.Equal(9, len(properties))
+	expectedProperties := map[string]any{
+		"SCH_HOST":    map[string]any{"type": "string", "description": "Name of the host"},
+		"SCH_PORT":    map[string]any{"type": "integer", "minimum": float64(0), "default": float64(8080)},
+		"SCH_RATE":    map[string]any{"type": "number", "default": 2.5},
+		"SCH_VERBOSE": map[string]any{"type": "boolean", "default": false},
+		"SCH_LEVEL":   map[string]any{"type": "string", "default": "info", "enum": []any{"debug", "info", "warn"}},
+		"SCH_TIMEOUT": map[string]any{"type": "string", "default": "1m"},
+		"SCH_TAGS":    map[string]any{"type": "array", "items": map[string]any{"type": "string"}, "default": []any{"a", "b"}},
+		"SCH_SIZES":   map[string]any{"type": "array", "items": map[string]any{"type": "integer", "enum": []any{float64(1), float64(2), float64(3)}}},
+		"SCH_KEY":     map[string]any{"type": "string", "writeOnly": true, "x-secret": "mask"},
+	}
+	for name, expectedProperty := range expectedProperties {
+		requirer.Equal(expectedProperty, properties[name], name)
+	}
+
+	// interface fields holding nil are described as strings
+	type anyConfig struct {
+		Value any `env:"SCH_ANY"`
+	}
+	anySchema, schemaErr := ConfigSchema(anyConfig{})
+	requirer.NoError(schemaErr)
+	requirer.Equal(&SchemaProperty{Type: "string"}, anySchema.Properties["SCH_ANY"])
+
+	_, schemaErr = ConfigSchema(42)
+	requirer.ErrorContains(schemaErr, "unsupported config kind")
+}
+
+func TestSchemaFromManifest(t *testing.T) {
+
+	const manifest = `
+required: [MAN_HOST]
+properties:
+  MAN_HOST: {type: string, description: Name of the host}
+  MAN_PORT: {type: integer, minimum: 1, default: 8080}
+  MAN_DEBUG: {type: boolean}
+  MAN_LEVEL: {type: string, enum: [debug, info]}
+  MAN_SIZES: {type: array, items: {type: integer}, default: [1, 2]}
+  MAN_KEY: {type: string, writeOnly: true, enum: [a]}
+  MAN_TAGS: {type: array}
+`
+
+	requirer := require.New(t)
+
+	schema, readErr := ReadSchema(strings.NewReader(manifest))
+	requirer.NoError(readErr)
+
+	envItems := schema.ConfigEnvItems(map[string]any{"MAN_DEBUG": "true", "MAN_HOST": "example.com"})
+	requirer.Equal([]ConfigEnvItem{
+		{Name: "MAN_DEBUG", Val: true, Kind: reflect.Bool},
+		{Name: "MAN_HOST", Val: "example.com", Kind: reflect.String, Required: true, Description: "Name of the host"},
+		{Name: "MAN_KEY", Val: "", Kind: reflect.String, Secret: SecretMask, Options: []string{"a"}},
+		{Name: "MAN_LEVEL", Val: "", Kind: reflect.String, Options: []string{"debug", "info"}},
+		{Name: "MAN_PORT", Val: int64(8080), Kind: reflect.Int64, Default: "8080"},
+		{Name: "MAN_SIZES", Val: "1,2", Kind: reflect.Slice, Default: "1,2"},
+		{Name: "MAN_TAGS", Val: "", Kind: reflect.Slice},
+	}, envItems)
+
+	// integers are decimal, whatever their leading zeros
+	envItems = schema.ConfigEnvItems(map[string]any{"MAN_PORT": "0042"})
+	requirer.Equal(int64(42), envItems[4].Val)
+	requirer.NoError(schema.ValidateValue("MAN_PORT", "08"))
+	requirer.NoError(schema.ValidateValue("MAN_TAGS", "a,b"))
+
+	requirer.NoError(schema.Validate(map[string]any{"MAN_HOST": "h", "MAN_PORT": "80", "MAN_SIZES": "3,4", "OTHER": "x"}))
+	validateErr := schema.Validate(map[string]any{"MAN_PORT": "0", "MAN_DEBUG": "maybe", "MAN_LEVEL": "warn", "MAN_SIZES": "1,two"})
+	requirer.EqualError(validateErr, `invalid(MAN_HOST): missing required value
+invalid value("maybe") for(MAN_DEBUG): isn't of type(boolean)
+invalid value("warn") for(MAN_LEVEL): isn't one of [debug info]
+invalid value("0") for(MAN_PORT): is less than 1
+invalid value("1,two") for(MAN_SIZES): item 2 isn't of type(integer)`)
+	requirer.ErrorIs(validateErr, ErrMissingRequired)
+	var fieldErr *FieldError
+	requirer.ErrorAs(validateErr, &fieldErr)
+	requirer.Equal("MAN_HOST", fieldErr.EnvName)
+	requirer.ErrorIs(schema.ValidateValue("NONESUCH", ""), ErrNotFound)
+	requirer.EqualError(schema.ValidateValue("MAN_KEY", "secret"), `invalid value("******") for(MAN_KEY): isn't one of [a]`)
+
+	_, readErr = ReadSchema(strings.NewReader("properties:\n  BAD: {type: object}\n"))
+	requirer.ErrorContains(readErr, "unsupported schema type for(BAD)")
+	_, readErr = ReadSchema(strings.NewReader("properties:\n  BAD: {type: array, items: {type: array}}\n"))
+	requirer.ErrorContains(readErr, "unsupported schema items type for(BAD)")
+	_, readErr = ReadSchema(strings.NewReader("properties:\n  BAD: {type: string, items: {type: string}}\n"))
+	requirer.ErrorContains(readErr, "unsupported schema items type for(BAD)")
+}
+
+func TestSchemaRoundTrip(t *testing.T) {
+
+	type testConfig struct {
+		Port  int    `env:"RT_PORT,default=80"`
+		Level string `env:"RT_LEVEL,required" enum:"debug,info"`
+	}
+
+	requirer := require.New(t)
+
+	writer := &bytes.Buffer{}
+	requirer.NoError(WriteConfigSchema(writer, testConfig{}))
+	schema, readErr := ReadSchema(writer)
+	requirer.NoError(readErr)
+
+	envItems, getterErr := GetConfigEnvItems(testConfig{Port: 80})
+	requirer.NoError(getterErr)
+	requirer.Equal([]ConfigEnvItem{envItems[1], {Name: "RT_PORT", Val: int64(80), Kind: reflect.Int64, Default: "80"}},
+		schema.ConfigEnvItems(map[string]any{}))
+}
@@ -0,0 +1,155 @@
+package configurator
+
+import (
+	"encoding/base64"
+	"errors"
+	"fmt"
+	"io/fs"
+	"os"
+	"strings"
+
+	"github.com/joho/godotenv"
+	"github.com/sethvargo/go-envconfig"
+)
+
+// SecretStore holds the values of configuration items tagged as `secret` apart
+// from the configuration file.  See WithSecretStore.
+type SecretStore interface {
+	// LoadSecrets returns all values held by the store, keyed by environment name
+	LoadSecrets() (map[string]string, error)
+	// SaveSecrets replaces all values held by the store with 'secrets'
+	SaveSecrets(secrets map[string]string) error
+}
+
+// SecretStoreLookuper returns an envconfig.Lookuper resolving environment names
+// to the values held in 'store'
+func SecretStoreLookuper(store SecretStore) (envconfig.Lookuper, error) {
+	secrets, loadErr := store.LoadSecrets()
+	if loadErr != nil {
+		return nil, loadErr
+	}
+	return envconfig.MapLookuper(secrets), nil
+}
+
+// updateSecrets merges 'secrets' into the values already held in 'store'
+func updateSecrets(store SecretStore, secrets map[string]string) error {
+	storedSecrets, loadErr := store.LoadSecrets()
+	if loadErr != nil {
+		return loadErr
+	}
+	for envName, secret := range secrets {
+		storedSecrets[envName] = secret
+	}
+	return store.SaveSecrets(storedSecrets)
+}
+
+// FileSecretStore keeps secrets in a dotenv formatted file, readable only by its owner
+type FileSecretStore struct {
+	FileName string
+}
+
+// LoadSecrets returns the secrets held in the file; a missing file holds no secrets
+func (fss *FileSecretStore) LoadSecrets() (map[string]string, error) {
+	contents, readErr := readSecretFile(fss.FileName)
+	if readErr != nil || contents == nil {
+		return map[string]string{}, readErr
+	}
+	return godotenv.UnmarshalBytes(contents)
+}
+
+// SaveSecrets replaces the contents of the file with 'secrets'
+func (fss *FileSecretStore) SaveSecrets(secrets map[string]string) error {
+	return writeSecretFile(fss.FileName, []byte(marshalDotenv(secrets)))
+}
+
+// encryptedSecretsPrefix identifies the format of an EncryptedFileSecretStore file
+const encryptedSecretsPrefix = "configurator:secrets:v1:"
+
+// EncryptedFileSecretStore keeps secrets in a file, readable only by its owner,
+// encrypted using a key derived from Passphrase
+type EncryptedFileSecretStore struct {
+	FileName   string
+	Passphrase []byte
+}
+
+// LoadSecrets returns the secrets held in the file; a missing file holds no secrets
+func (efss *EncryptedFileSecretStore) LoadSecrets() (map[string]string, error) {
+	contents, readErr := readSecretFile(efss.FileName)
+	if readErr != nil || contents == nil {
+		return map[string]string{}, readErr
+	}
+
+	encoded, hasPrefix := strings.CutPrefix(strings.TrimSpace(string(contents)), encryptedSecretsPrefix)
+	encodedParts := strings.Split(encoded, ":")
+	if !hasPrefix || len(encodedParts) != 2 {
+		return nil, fmt.Errorf("unrecognized format of secrets file(%s)", efss.FileName)
+	}
+	salt, saltErr := base64.StdEncoding.DecodeString(encodedParts[0])
+	if saltErr != nil {
+		return nil, fmt.Errorf("unrecognized format of secrets file(%s): %w", efss.FileName, saltErr)
+	}
+	sealed, sealedErr := base64.StdEncoding.DecodeString(encodedParts[1])
+	if sealedErr != nil {
+		return nil, fmt.Errorf("unrecognized format of secrets file(%s): %w", efss.FileName, sealedErr)
+	}
+
+	key, keyErr := deriveKey(efss.Passphrase, salt)
+	if keyErr != nil {
+		return nil, keyErr
+	}
+	plaintext, unsealErr := unseal(key, sealed, nil)
+	if unsealErr != nil {
+		return nil, fmt.Errorf("secrets file(%s): %w", efss.FileName, unsealErr)
+	}
+	return godotenv.UnmarshalBytes(plaintext)
+}
+
+// SaveSecrets replaces the contents of the file with 'secrets', encrypted using
+// a key derived from Passphrase and a newly generated salt
+func (efss *EncryptedFileSecretStore) SaveSecrets(secrets map[string]string) error {
+	plaintext := marshalDotenv(secrets)
+	salt, saltErr := newSalt()
+	if saltErr != nil {
+		return saltErr
+	}
+	key, keyErr := deriveKey(efss.Passphrase, salt)
+	if keyErr != nil {
+		return keyErr
+	}
+	sealed, sealErr := seal(key, []byte(plaintext), nil)
+	if sealErr != nil {
+		return sealErr
+	}
+	contents := encryptedSecretsPrefix + base64.StdEncoding.EncodeToString(salt) + ":" +
+		base64.StdEncoding.EncodeToString(sealed) + "\n"
+	return writeSecretFile(efss.FileName, []byte(contents))
+}
+
+// readSecretFile returns the contents of 'fileName', or nil if it doesn't exist
+func readSecretFile(fileName string) ([]byte, error) {
+	contents, readErr := os.ReadFile(fileName)
+	if errors.Is(readErr, fs.ErrNotExist) {
+		return nil, nil
+	}
+	return contents, readErr
+}
+
+// writeSecretFile replaces the contents of 'fileName', ensuring only its owner can access it
+func writeSecretFile(fileName string, contents []byte) (err error) {
+	secretFile, openErr := os.OpenFile(fileName, os.O_CREATE|os.O_WRONLY|os.O_TRUNC, 0600)
+	if openErr != nil {
+		return openErr
+	}
+	defer func() {
+		// the contents may not have been written
+		if closeErr := secretFile.Close(); closeErr != nil && err == nil {
+			err = closeErr
+		}
+	}()
+	// the file may have pre-existed with broader permissions
+	if chmodErr := secretFile.Chmod(0600); chmodErr != nil {
+		return chmodErr
+	}
+	_, writeErr := secretFile.Write(contents)
+	return writeErr
+}
@@ -0,0 +1,112 @@
+package configurator
+
+import (
+	"os"
+	"path/filepath"
+	"testing"
+
+	"github.com/stretchr/testify/require"
+)
+
+func TestSecretStores(t *testing.T) {
+
+	testCases := []struct {
+		name  string
+		store func(dir string) SecretStore
+	}{
+		{
+			name: "file",
+			store: func(dir string) SecretStore {
+				return &FileSecretStore{FileName: filepath.Join(dir, "secrets.env")}
+			},
+		},
+		{
+			name: "encrypted file",
+			store: func(dir string) SecretStore {
+				return &EncryptedFileSecretStore{FileName: filepath.Join(dir, "secrets.enc"), Passphrase: []byte("open sesame")}
+			},
+		},
+	}
+
+	for _, tc := range testCases {
+		t.Run(tc.name, func(t *testing.T) {
+			requirer := require.New(t)
+			store := tc.store(t.TempDir())
+
+			// a missing file holds no secrets
+			secrets, loadErr := store.LoadSecrets()
+			requirer.NoError(loadErr)
+			requirer.Empty(secrets)
+
+			savedSecrets := map[string]string{"ACCESS_KEY": "for 'your' \"eyes\" only\n# ${HOME} \\! `$x`", "PIN": "0042"}
+			requirer.NoError(store.SaveSecrets(savedSecrets))
+			secrets, loadErr = store.LoadSecrets()
+			requirer.NoError(loadErr)
+			requirer.Equal(savedSecrets, secrets)
+
+			lookuper, lookuperErr := SecretStoreLookuper(store)
+			requirer.NoError(lookuperErr)
+			pin, found := lookuper.Lookup("PIN")
+			requirer.True(found)
+			requirer.Equal("0042", pin)
+		})
+	}
+}
+
+func TestEncryptedFileSecretStore(t *testing.T) {
+
+	requirer := require.New(t)
+	secretsFileName := filepath.Join(t.TempDir(), "secrets.enc")
+	requirer.NoError(os.WriteFile(secretsFileName, []byte("world readable"), 0644))
+
+	store := &EncryptedFileSecretStore{FileName: secretsFileName, Passphrase: []byte("open sesame")}
+	_, loadErr := store.LoadSecrets()
+	requirer.ErrorContains(loadErr, "unrecognized format")
+
+	requirer.NoError(store.SaveSecrets(map[string]string{"ACCESS_KEY": "forYourEyesOnly"}))
+	contents, readErr := os.ReadFile(secretsFileName)
+	requirer.NoError(readErr)
+	requirer.NotContains(string(contents), "forYourEyesOnly")
+	fileInfo, statErr := os.Stat(secretsFileName)
+	requirer.NoError(statErr)
+	requirer.Equal(os.FileMode(0600), fileInfo.Mode().Perm())
+
+	wrongStore := &EncryptedFileSecretStore{FileName: secretsFileName, Passphrase: []byte("open barley")}
+	_, loadErr = wrongStore.LoadSecrets()
+	requirer.ErrorContains(loadErr, "wrong key")
+}
+
+func TestApiSaveLoadWithSecretStore(t *testing.T) {
+
+	type testConfig struct {
+		S1 string `env:"SS_S1"`
+		S2 string `env:"SS_S2,required" secret:"hide"`
+	}
+
+	requirer := require.New(t)
+	t.Setenv("SS_S1", "")
+	t.Setenv("SS_S2", "")
+
+	envFileName, ctefErr := createTempEnvFileFromMap(t, map[string]any{"SS_S1": "plain", "SS_S2": "forYourEyesOnly"})
+	requirer.NoError(ctefErr)
+	store := &FileSecretStore{FileName: filepath.Join(t.TempDir(), "secrets.env")}
+	requirer.NoError(store.SaveSecrets(map[string]string{"UNRELATED": "kept"}))
+
+	// saving moves the secret out of the configuration file and the environment
+	requirer.NoError(SaveConfig(envFileName, testConfig{S1: "plain", S2: "forYourEyesOnly"}, WithSecretStore(store)))
+	contents, readErr := os.ReadFile(envFileName)
+	requirer.NoError(readErr)
+	requirer.Equal("SS_S1=plain\n", string(contents))
+	_, found := os.LookupEnv("SS_S2")
+	requirer.False(found)
+	secrets, loadErr := store.LoadSecrets()
+	requirer.NoError(loadErr)
+	requirer.Equal(map[string]string{"SS_S2": "forYourEyesOnly", "UNRELATED": "kept"}, secrets)
+
+	// loading reads it back from the store
+	config := testConfig{}
+	requirer.NoError(LoadConfig(envFileName, &config, WithSecretStore(store)))
+	requirer.Equal(testConfig{S1: "plain", S2: "forYourEyesOnly"}, config)
+	_, found = os.LookupEnv("SS_S2")
+	requirer.False(found)
+}
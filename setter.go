@@ -3,13 +3,16 @@ package configurator
 import (
 	"fmt"
 	"reflect"
+	"slices"
 	"strconv"
 	"strings"
 )
 
-// SetConfigEnvItem allows setting in-place config values by the Name of their corresponding environment variable.
-// See https://go.dev/blog/laws-of-reflection and https://research.swtch.com/interfaces
-func SetConfigEnvItem[T any](config *T, envName, newValueAsString string) error {
+// SetConfigEnvItem allows setting in-place config values by the Name (or a deprecated alias) of their corresponding environment variable.
+// Since 'config' is modified in place, it mustn't be in use by other goroutines; see ConfigHolder for a way to
+// update configuration being used concurrently.  A FieldError is returned if the value can't be set, wrapping
+// ErrNotFound if no item has the name 'envName'.  See https://go.dev/blog/laws-of-reflection and https://research.swtch.com/interfaces
+func SetConfigEnvItem[T any](config *T, envName, newValueAsString string, opts ...Option) error {
 	cfgStructType, cfgStructElements, getConfigInfoErr := getConfigStructInfo(config)
 	if getConfigInfoErr != nil {
 		return getConfigInfoErr
@@ -27,13 +30,28 @@ func SetConfigEnvItem[T any](config *T, envName, newValueAsString string) error
 		}
 
 		tagParts := strings.Split(cfgStructFieldEnvTagValue, ",")
-		if len(tagParts) == 0 || envName != tagParts[0] {
+		if len(tagParts) == 0 {
 			continue
 		}
+		if envName != tagParts[0] {
+			if !slices.Contains(getAliases(cfgStructFieldTag), envName) {
+				continue
+			}
+			newOptions(opts).logger.Warn("deprecated name; use the current name instead", "env", tagParts[0], "alias", envName)
+		}
+
+		// errors are reported for the current name of the item, noting the alias it was given by
+		fieldError := func(value, secret string, err error) error {
+			fieldErr := newFieldError(tagParts[0], value, secret, err)
+			if envName != tagParts[0] {
+				fieldErr.Err = fmt.Errorf("%w (set as %s)", fieldErr.Err, envName)
+			}
+			return fieldErr
+		}
 
 		cfgStructFieldElement := cfgStructElements.Field(fieldIndex)
 		if !cfgStructFieldElement.CanSet() {
-			return fmt.Errorf("can't set(%s); not settable", envName)
+			return fmt.Errorf("can't set(%s); not settable", tagParts[0])
 		}
 
 		cfgStructFieldElementKind := cfgStructFieldElement.Kind()
@@ -44,7 +62,7 @@ func SetConfigEnvItem[T any](config *T, envName, newValueAsString string) error
 		case reflect.Bool:
 			parseBool, parseBoolErr := strconv.ParseBool(newValueAsString)
 			if parseBoolErr != nil {
-				return parseBoolErr
+				return fieldError(newValueAsString, cfgStructFieldTag.Get("secret"), parseBoolErr)
 			}
 			cfgStructFieldElement.SetBool(parseBool)
 			isSet = true
@@ -52,7 +70,7 @@ func SetConfigEnvItem[T any](config *T, envName, newValueAsString string) error
 			parseFloat, parseFloatErr := strconv.ParseFloat(newValueAsString,
 				map[reflect.Kind]int{reflect.Float64: 64, reflect.Float32: 32}[cfgStructFieldElementKind])
 			if parseFloatErr != nil {
-				return parseFloatErr
+				return fieldError(newValueAsString, cfgStructFieldTag.Get("secret"), parseFloatErr)
 			}
 			cfgStructFieldElement.SetFloat(parseFloat)
 			isSet = true
@@ -60,7 +78,7 @@ func SetConfigEnvItem[T any](config *T, envName, newValueAsString string) error
 			parseInt, parseIntErr := strconv.ParseInt(newValueAsString, 10,
 				map[reflect.Kind]int{reflect.Int: strconv.IntSize, reflect.Int64: 64, reflect.Int32: 32, reflect.Int16: 16, reflect.Int8: 8}[cfgStructFieldElementKind])
 			if parseIntErr != nil {
-				return parseIntErr
+				return fieldError(newValueAsString, cfgStructFieldTag.Get("secret"), parseIntErr)
 			}
 			cfgStructFieldElement.SetInt(parseInt)
 			isSet = true
@@ -68,17 +86,17 @@ func SetConfigEnvItem[T any](config *T, envName, newValueAsString string) error
 			parseUint, parseUintErr := strconv.ParseUint(newValueAsString, 10,
 				map[reflect.Kind]int{reflect.Uint: strconv.IntSize, reflect.Uint64: 64, reflect.Uint32: 32, reflect.Uint16: 16, reflect.Uint8: 8}[cfgStructFieldElementKind])
 			if parseUintErr != nil {
-				return parseUintErr
+				return fieldError(newValueAsString, cfgStructFieldTag.Get("secret"), parseUintErr)
 			}
 			cfgStructFieldElement.SetUint(parseUint)
 			isSet = true
 		default:
-			return fmt.Errorf("unrecognized Kind(%v)", cfgStructFieldElementKind)
+			return fieldError("", "", fmt.Errorf("unrecognized Kind(%v)", cfgStructFieldElementKind))
 		}
 		break
 	}
 	if !isSet {
-		return fmt.Errorf("env value(%s) wan't set; not found", envName)
+		return &FieldError{EnvName: envName, Err: ErrNotFound}
 	}
 	return nil
 }
@@ -3,6 +3,7 @@ package configurator
 import (
 	"fmt"
 	"math"
+	"strconv"
 	"testing"
 
 	"github.com/stretchr/testify/require"
@@ -84,3 +85,26 @@ func TestSetConfigItem(t *testing.T) {
 	}
 
 }
+
+func TestSetConfigItemByAlias(t *testing.T) {
+
+	type testConfig struct {
+		S1 string `env:"S1" aliases:"OLD_S1, LEGACY_S1"`
+		I2 int32  `env:"I2" aliases:"OLD_I2"`
+	}
+
+	requirer := require.New(t)
+
+	config := testConfig{}
+	requirer.NoError(SetConfigEnvItem(&config, "LEGACY_S1", "legacy", WithLogger(nil)))
+	requirer.Equal(testConfig{S1: "legacy"}, config)
+
+	// errors are reported for the current name, noting the alias
+	setErr := SetConfigEnvItem(&config, "OLD_I2", "x", WithLogger(nil))
+	var fieldErr *FieldError
+	requirer.ErrorAs(setErr, &fieldErr)
+	requirer.Equal("I2", fieldErr.EnvName)
+	requirer.EqualError(setErr, `invalid value("x") for(I2): invalid syntax (set as OLD_I2)`)
+	requirer.ErrorIs(setErr, strconv.ErrSyntax)
+	requirer.Equal(testConfig{S1: "legacy"}, config)
+}
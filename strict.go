@@ -0,0 +1,120 @@
+package configurator
+
+import (
+	"fmt"
+	"strings"
+)
+
+// UnknownKeyPolicy determines how LoadConfig treats entries of the configuration file
+// which don't correspond to any environment item of the configuration structure
+type UnknownKeyPolicy int
+
+const (
+	UnknownKeysIgnore UnknownKeyPolicy = iota // unknown keys are loaded into the environment (the default)
+	UnknownKeysWarn                           // unknown keys are noted, then loaded into the environment
+	UnknownKeysError                          // unknown keys cause LoadConfig to fail, before loading the file
+)
+
+// UnknownKeyError reports an entry of a configuration file which doesn't correspond to
+// any environment item of the configuration structure, suggesting the likely intended one
+type UnknownKeyError struct {
+	File       string
+	Key        string
+	Suggestion string // the name of the most similar environment item, if any is similar
+}
+
+func (uke *UnknownKeyError) Error() string {
+	if uke.Suggestion != "" {
+		return fmt.Sprintf("unknown key(%s) in %s; did you mean %s?", uke.Key, uke.File, uke.Suggestion)
+	}
+	return fmt.Sprintf("unknown key(%s) in %s", uke.Key, uke.File)
+}
+
+// checkUnknownKeys applies the unknown key policy to the entries of 'configFile' (and that
+// of the selected profile) not named in 'knownNames'; a missing or unreadable file is left
+// to be reported when loaded
+func checkUnknownKeys(configFile string, knownNames []string, o *options) error {
+	if o.unknownKeys == UnknownKeysIgnore {
+		return nil
+	}
+	isKnown := make(map[string]bool, len(knownNames))
+	for _, knownName := range knownNames {
+		isKnown[knownName] = true
+	}
+
+	var unknownKeyErrs []error
+	for _, fileName := range o.configFiles(configFile) {
+		fileEnv, fileSources, readErr := readConfigFileEntries(fileName, o)
+		if readErr != nil {
+			continue
+		}
+		for _, key := range sortedKeys(fileEnv) {
+			if isKnown[key] {
+				continue
+			}
+			// the key may be that of an included file
+			keyFile := fileSources[key].File
+			unknownKeyErr := &UnknownKeyError{File: keyFile, Key: key, Suggestion: suggestName(key, knownNames)}
+			if o.unknownKeys == UnknownKeysWarn {
+				o.logger.Warn("unknown key", "file", keyFile, "env", key, "suggestion", unknownKeyErr.Suggestion)
+				continue
+			}
+			unknownKeyErrs = append(unknownKeyErrs, unknownKeyErr)
+		}
+	}
+	return newMultiError(unknownKeyErrs)
+}
+
+// getKnownNames returns the names of the entries of a configuration file holding 'config'
+func getKnownNames[T any](config T, o *options) ([]string, error) {
+	envItems, getterErr := GetConfigEnvItems(config)
+	if getterErr != nil {
+		return nil, getterErr
+	}
+	var knownNames []string
+	for _, envItem := range envItems {
+		knownNames = append(knownNames, envItem.Name)
+		knownNames = append(knownNames, envItem.Aliases...)
+		if o.fileIndirection {
+			knownNames = append(knownNames, envItem.Name+FileIndirectionSuffix)
+		}
+	}
+	if len(o.migrations) != 0 {
+		knownNames = append(knownNames, o.versionKey)
+	}
+	return knownNames, nil
+}
+
+// suggestName returns the one of 'knownNames' most similar to 'name' (ignoring case),
+// provided it's similar enough to likely be what was intended, otherwise ""
+func suggestName(name string, knownNames []string) string {
+	suggestion, bestDistance := "", max(2, len(name)/3)+1
+	for _, knownName := range knownNames {
+		if distance := editDistance(strings.ToUpper(name), strings.ToUpper(knownName)); distance < bestDistance {
+			suggestion, bestDistance = knownName, distance
+		}
+	}
+	return suggestion
+}
+
+// editDistance returns the Levenshtein distance between 's1' and 's2'
+func editDistance(s1, s2 string) int {
+	r1, r2 := []rune(s1), []rune(s2)
+	previous := make([]int, len(r2)+1)
+	for j := range previous {
+		previous[j] = j
+	}
+	for i := 1; i <= len(r1); i++ {
+		current := make([]int, len(r2)+1)
+		current[0] = i
+		for j := 1; j <= len(r2); j++ {
+			substitutionCost := 1
+			if r1[i-1] == r2[j-1] {
+				substitutionCost = 0
+			}
+			current[j] = min(previous[j]+1, current[j-1]+1, previous[j-1]+substitutionCost)
+		}
+		previous = current
+	}
+	return previous[len(r2)]
+}
@@ -0,0 +1,61 @@
+package configurator
+
+import (
+	"bytes"
+	"errors"
+	"log/slog"
+	"os"
+	"testing"
+
+	"github.com/stretchr/testify/require"
+)
+
+func TestApiLoadUnknownKeys(t *testing.T) {
+
+	type testConfig struct {
+		Rate float64 `env:"STRICT_CONVERSION_RATE,default=3.14"`
+		Name string  `env:"STRICT_NAME" aliases:"STRICT_OLD_NAME"`
+	}
+
+	requirer := require.New(t)
+	unsetTestEnv(t, "STRICT_CONVERSION_RATE", "STRICT_CONVERSON_RATE", "STRICT_OLD_NAME", "STRICT_XYZZY")
+
+	envFileName, ctefErr := createTempEnvFileFromMap(t, map[string]any{
+		"STRICT_CONVERSON_RATE": "2",
+		"STRICT_OLD_NAME":       "n",
+		"STRICT_XYZZY":          "?",
+	})
+	requirer.NoError(ctefErr)
+	for _, envName := range []string{"STRICT_CONVERSON_RATE", "STRICT_OLD_NAME", "STRICT_XYZZY"} {
+		requirer.NoError(unsetManagedEnv(envName))
+	}
+
+	config := testConfig{}
+	loadErr := LoadConfig(envFileName, &config, WithUnknownKeys(UnknownKeysError))
+	requirer.EqualError(loadErr, "unknown key(STRICT_CONVERSON_RATE) in "+envFileName+"; did you mean STRICT_CONVERSION_RATE?\n"+
+		"unknown key(STRICT_XYZZY) in "+envFileName)
+	var unknownKeyErr *UnknownKeyError
+	requirer.True(errors.As(loadErr, &unknownKeyErr))
+	requirer.Equal(&UnknownKeyError{File: envFileName, Key: "STRICT_CONVERSON_RATE", Suggestion: "STRICT_CONVERSION_RATE"}, unknownKeyErr)
+	// the file isn't loaded
+	_, found := os.LookupEnv("STRICT_CONVERSON_RATE")
+	requirer.False(found)
+
+	logBuffer := &bytes.Buffer{}
+	requirer.NoError(LoadConfig(envFileName, &config, WithUnknownKeys(UnknownKeysWarn), WithLogger(slog.New(slog.NewTextHandler(logBuffer, nil)))))
+	requirer.Equal(testConfig{Rate: 3.14, Name: "n"}, config)
+	requirer.Contains(logBuffer.String(), "level=WARN msg=\"unknown key\" file="+envFileName+" env=STRICT_CONVERSON_RATE suggestion=STRICT_CONVERSION_RATE")
+	requirer.Contains(logBuffer.String(), "level=WARN msg=\"unknown key\" file="+envFileName+" env=STRICT_XYZZY suggestion=\"\"")
+}
+
+func TestSuggestName(t *testing.T) {
+
+	requirer := require.New(t)
+
+	knownNames := []string{"CONVERSION_RATE", "ACCESS_KEY", "LAST4_SSN", "FPG"}
+	requirer.Equal("CONVERSION_RATE", suggestName("CONVERSON_RATE", knownNames))
+	requirer.Equal("ACCESS_KEY", suggestName("access_key", knownNames))
+	requirer.Equal("FPG", suggestName("FGP", knownNames))
+	requirer.Equal("", suggestName("RUNDABLE", knownNames))
+	requirer.Equal(3, editDistance("kitten", "sitting"))
+}
@@ -0,0 +1,16 @@
+package configurator
+
+// Validator can be implemented by a configuration structure in order to have
+// its values checked whenever they're reloaded (see WatchConfig) or updated
+// (see ConfigHolder) while in use.
+type Validator interface {
+	Validate() error
+}
+
+// validateConfig validates 'config' if it implements Validator
+func validateConfig[T any](config *T) error {
+	if validator, ok := any(config).(Validator); ok {
+		return validator.Validate()
+	}
+	return nil
+}
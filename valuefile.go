@@ -0,0 +1,87 @@
+package configurator
+
+import (
+	"os"
+	"path/filepath"
+	"strings"
+
+	"github.com/sethvargo/go-envconfig"
+)
+
+// FileIndirectionSuffix is appended to the name of an item to name the variable holding the
+// name of a file holding its value (e.g., "DB_PASSWORD_FILE=/run/secrets/db_password"); see
+// WithFileIndirection
+const FileIndirectionSuffix = "_FILE"
+
+// valueFileFor returns the name of the file holding the value of the item named 'envName',
+// if the options establish one: that named by its indirection variable, otherwise that
+// named 'envName' within the values directory, if it exists
+func (o *options) valueFileFor(envName string) (string, bool) {
+	if valueFile, found := o.indirectionFor(envName); found {
+		return valueFile, true
+	}
+	if o.valuesDir != "" {
+		valueFile := filepath.Join(o.valuesDir, envName)
+		if fileInfo, statErr := os.Stat(valueFile); statErr == nil && fileInfo.Mode().IsRegular() {
+			return valueFile, true
+		}
+	}
+	return "", false
+}
+
+// indirectionFor returns the name of the file named by the indirection variable of the item
+// named 'envName', if indirection is enabled (see WithFileIndirection) and it's set
+func (o *options) indirectionFor(envName string) (string, bool) {
+	if !o.fileIndirection {
+		return "", false
+	}
+	valueFile := os.Getenv(envName + FileIndirectionSuffix)
+	return valueFile, valueFile != ""
+}
+
+// loadValueFilesLookuper returns an envconfig.Lookuper resolving the environment names of
+// the items of 'config' to the values held in their value files (see valueFileFor), together
+// with the names of those files; an error is returned for each file which can't be read
+func loadValueFilesLookuper[T any](config T, o *options) (envconfig.Lookuper, map[string]string, error) {
+	envItems, getterErr := GetConfigEnvItems(config)
+	if getterErr != nil {
+		return nil, nil, getterErr
+	}
+	values := make(map[string]string)
+	valueFiles := make(map[string]string)
+	var readErrs []error
+	for _, envItem := range envItems {
+		valueFile, found := o.valueFileFor(envItem.Name)
+		if !found {
+			continue
+		}
+		value, readErr := readValueFile(valueFile)
+		if readErr != nil {
+			readErrs = append(readErrs, &FieldError{EnvName: envItem.Name, Err: readErr})
+			continue
+		}
+		values[envItem.Name] = value
+		valueFiles[envItem.Name] = valueFile
+	}
+	if len(readErrs) != 0 {
+		return nil, nil, newMultiError(readErrs)
+	}
+	return envconfig.MapLookuper(values), valueFiles, nil
+}
+
+// readValueFile returns the value held in 'valueFile', without its trailing newline
+func readValueFile(valueFile string) (string, error) {
+	contents, readErr := os.ReadFile(valueFile)
+	if readErr != nil {
+		return "", &FileError{File: valueFile, Err: readErr}
+	}
+	return trimNewline(string(contents)), nil
+}
+
+// trimNewline returns 's' without its trailing newline, if any
+func trimNewline(s string) string {
+	if trimmed, found := strings.CutSuffix(s, "\n"); found {
+		return strings.TrimSuffix(trimmed, "\r")
+	}
+	return s
+}
@@ -0,0 +1,119 @@
+package configurator
+
+import (
+	"bytes"
+	"io/fs"
+	"log/slog"
+	"os"
+	"path/filepath"
+	"testing"
+
+	"github.com/stretchr/testify/require"
+)
+
+func TestApiLoadSaveFileIndirection(t *testing.T) {
+
+	type testConfig struct {
+		User     string `env:"VF_USER"`
+		Password string `env:"VF_PASSWORD" secret:"mask"`
+	}
+
+	requirer := require.New(t)
+	unsetTestEnv(t, "VF_USER", "VF_PASSWORD", "VF_PASSWORD_FILE")
+
+	secretFileName := filepath.Join(t.TempDir(), "password")
+	requirer.NoError(os.WriteFile(secretFileName, []byte("s3cret\n"), 0400))
+	envFileName, ctefErr := createTempEnvFileFromMap(t, map[string]any{"VF_USER": "user", "VF_PASSWORD_FILE": secretFileName})
+	requirer.NoError(ctefErr)
+
+	// without indirection, the value isn't loaded
+	config := testConfig{}
+	requirer.NoError(LoadConfig(envFileName, &config))
+	requirer.Equal(testConfig{User: "user"}, config)
+
+	// with it, the value is loaded from the referenced file, without its trailing newline
+	config = testConfig{}
+	provenance := make(Provenance)
+	requirer.NoError(LoadConfig(envFileName, &config, WithFileIndirection(), WithProvenance(provenance), WithUnknownKeys(UnknownKeysError)))
+	requirer.Equal(testConfig{User: "user", Password: "s3cret"}, config)
+	requirer.Equal("value file "+secretFileName, provenance["VF_PASSWORD"].String())
+	_, found := os.LookupEnv("VF_PASSWORD")
+	requirer.False(found)
+
+	// the indirection and the (e.g., read-only) referenced file are left intact, with a changed value noted
+	config.Password = "n3w"
+	logBuffer := &bytes.Buffer{}
+	requirer.NoError(SaveConfig(envFileName, config, WithFileIndirection(), WithLogger(slog.New(slog.NewTextHandler(logBuffer, nil)))))
+	contents, readErr := os.ReadFile(envFileName)
+	requirer.NoError(readErr)
+	requirer.Equal("VF_PASSWORD_FILE="+secretFileName+"\nVF_USER=user\n", string(contents))
+	contents, readErr = os.ReadFile(secretFileName)
+	requirer.NoError(readErr)
+	requirer.Equal("s3cret\n", string(contents))
+	requirer.Contains(logBuffer.String(), `level=WARN msg="changed value held in a value file isn't saved" file=`+secretFileName)
+	requirer.NotContains(logBuffer.String(), "n3w")
+
+	// values set in the environment take precedence
+	t.Setenv("VF_PASSWORD", "from env")
+	config = testConfig{}
+	requirer.NoError(LoadConfig(envFileName, &config, WithFileIndirection()))
+	requirer.Equal("from env", config.Password)
+	requirer.NoError(os.Unsetenv("VF_PASSWORD"))
+
+	// a referenced file which can't be read is reported
+	requirer.NoError(os.Remove(secretFileName))
+	loadErr := LoadConfig(envFileName, &testConfig{}, WithFileIndirection())
+	var fieldErr *FieldError
+	requirer.ErrorAs(loadErr, &fieldErr)
+	requirer.Equal("VF_PASSWORD", fieldErr.EnvName)
+	requirer.ErrorIs(loadErr, fs.ErrNotExist)
+}
+
+func TestApiLoadSaveValuesDir(t *testing.T) {
+
+	type testConfig struct {
+		Host  string `env:"VD_HOST,default=localhost"`
+		Token string `env:"VD_TOKEN" secret:"true"`
+	}
+
+	requirer := require.New(t)
+	unsetTestEnv(t, "VD_HOST", "VD_TOKEN")
+
+	valuesDir := t.TempDir()
+	tokenFileName := filepath.Join(valuesDir, "VD_TOKEN")
+	requirer.NoError(os.WriteFile(tokenFileName, []byte("t0ken\r\n"), 0600))
+	requirer.NoError(os.Mkdir(filepath.Join(valuesDir, "VD_HOST"), 0700))
+	envFileName := filepath.Join(t.TempDir(), "config.env")
+
+	// each file within the directory holds the value of the item it names
+	config := testConfig{}
+	provenance := make(Provenance)
+	requirer.NoError(LoadConfig(envFileName, &config, WithValuesDir(valuesDir), WithProvenance(provenance)))
+	requirer.Equal(testConfig{Host: "localhost", Token: "t0ken"}, config)
+	requirer.Equal(SourceValueFile, provenance["VD_TOKEN"].Kind)
+	requirer.Equal(SourceDefault, provenance["VD_HOST"].Kind)
+
+	// whose values are saved there rather than into the configuration file
+	config.Token = "t0ken"
+	requirer.NoError(SaveConfig(envFileName, config, WithValuesDir(valuesDir)))
+	contents, readErr := os.ReadFile(envFileName)
+	requirer.NoError(readErr)
+	requirer.Equal("VD_HOST=localhost\n", string(contents))
+	contents, readErr = os.ReadFile(tokenFileName)
+	requirer.NoError(readErr)
+	requirer.Equal("t0ken\r\n", string(contents))
+
+	// values found in the configuration file take precedence, and are saved there
+	requirer.NoError(os.WriteFile(envFileName, []byte("VD_TOKEN=from file\n"), 0600))
+	config = testConfig{}
+	requirer.NoError(LoadConfig(envFileName, &config, WithValuesDir(valuesDir)))
+	requirer.Equal(testConfig{Host: "localhost", Token: "from file"}, config)
+	config.Token = "changed"
+	requirer.NoError(SaveConfig(envFileName, config, WithValuesDir(valuesDir)))
+	contents, readErr = os.ReadFile(envFileName)
+	requirer.NoError(readErr)
+	requirer.Equal("VD_HOST=localhost\nVD_TOKEN=changed\n", string(contents))
+	contents, readErr = os.ReadFile(tokenFileName)
+	requirer.NoError(readErr)
+	requirer.Equal("t0ken\r\n", string(contents))
+}
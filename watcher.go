@@ -0,0 +1,196 @@
+package configurator
+
+import (
+	"bytes"
+	"context"
+	"errors"
+	"io/fs"
+	"maps"
+	"os"
+	"slices"
+	"time"
+)
+
+// defaultWatchInterval is how often WatchConfig checks for changes unless overridden
+const defaultWatchInterval = time.Second
+
+// WatchConfig starts checking 'configFile' (along with the configuration file of the selected
+// profile, if any, and the files they include) for changes, until 'ctx' is done.  Upon each
+// change, the configuration is loaded into a new structure (see LoadConfig) and validated
+// (see Validator); if successful, it's passed to 'onChange', otherwise the error is passed
+// to 'onError' (if not nil), no new configuration is delivered, and the environment is left
+// as it was.  Since values loaded from the files are also loaded into the environment, the
+// variables loaded from entries no longer in the files are removed, unless set other than
+// by configurator.  'onChange' and 'onError' are called from the goroutine started by
+// WatchConfig to check for changes.
+func WatchConfig[T any](ctx context.Context, configFile string, onChange func(*T), onError func(error), opts ...Option) {
+	watchConfig(ctx, configFile, onChange, onError, nil, opts...)
+}
+
+// watchConfig watches 'configFile' as described by WatchConfig, except that changes for which
+// 'isOwnWrite' (if not nil) returns true, given the contents of the watched files, aren't loaded
+func watchConfig[T any](ctx context.Context, configFile string, onChange func(*T), onError func(error), isOwnWrite func(map[string][]byte) bool, opts ...Option) {
+	options := newOptions(opts)
+	reportErr := func(err error) {
+		if onError != nil {
+			onError(err)
+		}
+	}
+
+	lastContents, readErr := readWatchedFiles(watchedFiles(configFile, options))
+	if readErr != nil {
+		reportErr(readErr)
+	}
+	loadedNames := readLoadedNames(configFile, options)
+
+	go func() {
+		watchInterval := options.watchInterval
+		if watchInterval <= 0 {
+			watchInterval = defaultWatchInterval
+		}
+		ticker := time.NewTicker(watchInterval)
+		defer ticker.Stop()
+		for {
+			select {
+			case <-ctx.Done():
+				return
+			case <-ticker.C:
+			}
+
+			contents, readErr := readWatchedFiles(watchedFiles(configFile, options))
+			if readErr != nil {
+				reportErr(readErr)
+				continue
+			}
+			if maps.EqualFunc(contents, lastContents, sameWatchedContents) {
+				continue
+			}
+			lastContents = contents
+
+			newNames := readLoadedNames(configFile, options)
+			if isOwnWrite != nil && isOwnWrite(contents) {
+				// already delivered
+				loadedNames = newNames
+				continue
+			}
+			snapshot := takeEnvSnapshot(append(slices.Clone(loadedNames), newNames...))
+			unsetStaleEnv(loadedNames, newNames)
+			config := new(T)
+			reloadErr := LoadConfig(configFile, config, opts...)
+			if reloadErr == nil {
+				reloadErr = validateConfig(config)
+			}
+			if reloadErr != nil {
+				snapshot.restore()
+				reportErr(reloadErr)
+				continue
+			}
+			loadedNames = newNames
+			onChange(config)
+		}
+	}()
+}
+
+// watchedFiles returns the names of the files holding the configuration (see configFiles),
+// followed by those they include
+func watchedFiles(configFile string, o *options) []string {
+	var files []string
+	for _, fileName := range o.configFiles(configFile) {
+		files = append(files, fileName)
+		files = append(files, includedFiles(fileName, o)...)
+	}
+	return files
+}
+
+// readWatchedFiles returns the contents of 'fileNames', keyed by name (see readWatchedFile)
+func readWatchedFiles(fileNames []string) (map[string][]byte, error) {
+	contents := make(map[string][]byte, len(fileNames))
+	for _, fileName := range fileNames {
+		fileContents, readErr := readWatchedFile(fileName)
+		if readErr != nil {
+			return nil, readErr
+		}
+		contents[fileName] = fileContents
+	}
+	return contents, nil
+}
+
+// sameWatchedContents returns true unless the contents of a watched file have changed,
+// including being created or removed
+func sameWatchedContents(contents1, contents2 []byte) bool {
+	return bytes.Equal(contents1, contents2) && (contents1 == nil) == (contents2 == nil)
+}
+
+// readWatchedFile returns the contents of 'fileName', or nil if it doesn't exist
+func readWatchedFile(fileName string) ([]byte, error) {
+	contents, readErr := os.ReadFile(fileName)
+	if errors.Is(readErr, fs.ErrNotExist) {
+		return nil, nil
+	}
+	if readErr != nil {
+		return nil, readErr
+	}
+	if contents == nil {
+		// distinguishes an empty file from a missing one
+		contents = []byte{}
+	}
+	return contents, nil
+}
+
+// readLoadedNames returns the names of the entries loaded from the configuration files
+// (see readConfigLayers), if they can be read
+func readLoadedNames(configFile string, o *options) []string {
+	fileEnv, _, readErr := readConfigLayers(configFile, o)
+	if readErr != nil {
+		return nil
+	}
+	return sortedKeys(fileEnv)
+}
+
+// unsetStaleEnv removes the environment variables set by configurator from entries named in
+// 'loadedNames' but not in 'newNames', since they'd otherwise still be loaded
+func unsetStaleEnv(loadedNames, newNames []string) {
+	for _, envName := range loadedNames {
+		if slices.Contains(newNames, envName) {
+			continue
+		}
+		if _, isExternal := lookupExternalEnv(envName); !isExternal {
+			_ = unsetManagedEnv(envName)
+		}
+	}
+}
+
+// envSnapshot records the state of environment variables, by name
+type envSnapshot map[string]snapshotEnv
+
+type snapshotEnv struct {
+	val     string
+	found   bool
+	managed bool // set by configurator
+}
+
+// takeEnvSnapshot records the state of the environment variables named in 'envNames'
+func takeEnvSnapshot(envNames []string) envSnapshot {
+	snapshot := make(envSnapshot, len(envNames))
+	for _, envName := range envNames {
+		envVal, found := os.LookupEnv(envName)
+		_, isExternal := lookupExternalEnv(envName)
+		snapshot[envName] = snapshotEnv{val: envVal, found: found, managed: found && !isExternal}
+	}
+	return snapshot
+}
+
+// restore returns the environment variables recorded by the snapshot to their recorded state
+func (es envSnapshot) restore() {
+	for envName, se := range es {
+		switch {
+		case !se.found:
+			_ = unsetManagedEnv(envName)
+		case se.managed:
+			_ = setManagedEnv(envName, se.val)
+		default:
+			managedEnv.Delete(envName)
+			_ = os.Setenv(envName, se.val)
+		}
+	}
+}
@@ -0,0 +1,141 @@
+package configurator
+
+import (
+	"context"
+	"errors"
+	"os"
+	"path/filepath"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/require"
+)
+
+type watchedTestConfig struct {
+	S1 string `env:"WATCH_S1,required"`
+	I2 int    `env:"WATCH_I2,default=1"`
+}
+
+func (wtc *watchedTestConfig) Validate() error {
+	if wtc.I2 < 0 {
+		return errors.New("WATCH_I2 must not be negative")
+	}
+	return nil
+}
+
+func TestWatchConfig(t *testing.T) {
+
+	requirer := require.New(t)
+	unsetTestEnv(t, "WATCH_S1", "WATCH_I2")
+
+	envFileName, ctefErr := createTempEnvFileFromMap(t, map[string]any{"WATCH_S1": "first"})
+	requirer.NoError(ctefErr)
+	config := &watchedTestConfig{}
+	requirer.NoError(LoadConfig(envFileName, config))
+	requirer.Equal("first", config.S1)
+
+	ctx, cancel := context.WithCancel(context.Background())
+	defer cancel()
+	changes := make(chan *watchedTestConfig, 10)
+	errs := make(chan error, 10)
+	WatchConfig(ctx, envFileName, func(c *watchedTestConfig) { changes <- c }, func(err error) { errs <- err },
+		WithWatchInterval(5*time.Millisecond))
+
+	awaitChange := func() *watchedTestConfig {
+		select {
+		case c := <-changes:
+			return c
+		case err := <-errs:
+			requirer.FailNow("unexpected error", err)
+		case <-time.After(5 * time.Second):
+			requirer.FailNow("timed out awaiting change")
+		}
+		return nil
+	}
+	awaitError := func() error {
+		select {
+		case c := <-changes:
+			requirer.FailNow("unexpected change", c)
+		case err := <-errs:
+			return err
+		case <-time.After(5 * time.Second):
+			requirer.FailNow("timed out awaiting error")
+		}
+		return nil
+	}
+
+	// a changed file is reloaded, even though its previous values were loaded into the environment
+	requirer.NoError(os.WriteFile(envFileName, []byte("WATCH_S1=second\nWATCH_I2=2\n"), 0600))
+	requirer.Equal(&watchedTestConfig{S1: "second", I2: 2}, awaitChange())
+
+	// invalid configurations aren't delivered
+	requirer.NoError(os.WriteFile(envFileName, []byte("WATCH_S1=third\nWATCH_I2=-3\n"), 0600))
+	requirer.ErrorContains(awaitError(), "must not be negative")
+	requirer.NoError(os.WriteFile(envFileName, []byte("WATCH_I2=4\n"), 0600))
+	requirer.ErrorContains(awaitError(), "missing required value")
+
+	requirer.NoError(os.WriteFile(envFileName, []byte("WATCH_S1=fifth\n"), 0600))
+	requirer.Equal(&watchedTestConfig{S1: "fifth", I2: 1}, awaitChange())
+
+	// values exported into the environment by other means still take precedence
+	t.Setenv("WATCH_I2", "6")
+	requirer.NoError(os.WriteFile(envFileName, []byte("WATCH_S1=sixth\nWATCH_I2=7\n"), 0600))
+	requirer.Equal(&watchedTestConfig{S1: "sixth", I2: 6}, awaitChange())
+}
+
+func TestWatchConfigEnv(t *testing.T) {
+
+	requirer := require.New(t)
+	unsetTestEnv(t, "WATCH_I2", DefaultProfileEnv)
+	// e.g., exported by the user's shell
+	t.Setenv("WATCH_S1", "first")
+
+	tempDir := t.TempDir()
+	envFileName := filepath.Join(tempDir, "config.env")
+	commonFileName := filepath.Join(tempDir, "common.env")
+	prodFileName := filepath.Join(tempDir, "config.prod.env")
+	requirer.NoError(os.WriteFile(commonFileName, []byte("WATCH_I2=2\n"), 0600))
+	requirer.NoError(os.WriteFile(envFileName, []byte("#include common.env\nWATCH_S1=first\n"), 0600))
+	t.Setenv(DefaultProfileEnv, "prod")
+	requirer.NoError(LoadConfig(envFileName, &watchedTestConfig{}, WithLogger(nil)))
+
+	ctx, cancel := context.WithCancel(context.Background())
+	defer cancel()
+	changes := make(chan *watchedTestConfig, 10)
+	errs := make(chan error, 10)
+	WatchConfig(ctx, envFileName, func(c *watchedTestConfig) { changes <- c }, func(err error) { errs <- err },
+		WithWatchInterval(5*time.Millisecond), WithLogger(nil))
+
+	awaitChange := func() *watchedTestConfig {
+		select {
+		case c := <-changes:
+			return c
+		case err := <-errs:
+			requirer.FailNow("unexpected error", err)
+		case <-time.After(5 * time.Second):
+			requirer.FailNow("timed out awaiting change")
+		}
+		return nil
+	}
+
+	// variables exported other than by configurator aren't removed, even if matching a removed entry
+	requirer.NoError(os.WriteFile(envFileName, []byte("#include common.env\n"), 0600))
+	requirer.Equal(&watchedTestConfig{S1: "first", I2: 2}, awaitChange())
+	requirer.Equal("first", os.Getenv("WATCH_S1"))
+
+	// changes to included files and the profile's file are reloaded
+	requirer.NoError(os.WriteFile(commonFileName, []byte("WATCH_I2=3\n"), 0600))
+	requirer.Equal(&watchedTestConfig{S1: "first", I2: 3}, awaitChange())
+	requirer.NoError(os.WriteFile(prodFileName, []byte("WATCH_I2=4\n"), 0600))
+	requirer.Equal(&watchedTestConfig{S1: "first", I2: 4}, awaitChange())
+
+	// a failed reload leaves the environment unchanged
+	requirer.NoError(os.WriteFile(prodFileName, []byte("WATCH_I2=-5\n"), 0600))
+	select {
+	case err := <-errs:
+		requirer.ErrorContains(err, "must not be negative")
+	case <-time.After(5 * time.Second):
+		requirer.FailNow("timed out awaiting error")
+	}
+	requirer.Equal("4", os.Getenv("WATCH_I2"))
+}